@@ -0,0 +1,144 @@
+// Gotilities - base64
+// Author: prbrown
+//
+// Base64 encode or decode the concatenated input, as GNU base64 does.
+package main
+
+import "io"
+import "errors"
+import "encoding/base64"
+
+const BASE64_WRAP int = 76
+
+// Base64Encode writes src to dst in base64, in lines of 76 characters.
+func Base64Encode(dst io.Writer, src io.Reader) error {
+   return filter_copy(new_base64_encoder(dst), src)
+}
+
+// Base64Decode writes the decoding of base64 text in src to dst. Newlines
+// and other whitespace in the input are ignored.
+func Base64Decode(dst io.Writer, src io.Reader) error {
+   return filter_copy(new_base64_decoder(dst), src)
+}
+
+// copies src into an output filter and closes it
+func filter_copy(w io.WriteCloser, src io.Reader) error {
+   _, ok := io.CopyBuffer(w, src, make([]byte, IO_BLK_SIZE_DEFAULT))
+   if close_ok := w.Close(); ok == nil {
+      ok = close_ok
+   }
+   return ok
+}
+
+// wrap_writer breaks what is written to it into lines of width bytes
+type wrap_writer struct {
+   dst io.Writer
+   width int
+   column int
+}
+
+func (w *wrap_writer) Write(p []byte) (int, error) {
+   n := len(p)
+   for len(p) > 0 {
+      room := w.width - w.column
+      if room > len(p) {
+         room = len(p)
+      }
+      if _, ok := w.dst.Write(p[:room]); ok != nil {
+         return 0, ok
+      }
+      p = p[room:]
+
+      w.column += room
+      if w.column == w.width {
+         if _, ok := w.dst.Write([]byte{'\n'}); ok != nil {
+            return 0, ok
+         }
+         w.column = 0
+      }
+   }
+   return n, nil
+}
+
+// Close ends a partial last line
+func (w *wrap_writer) Close() error {
+   if w.column > 0 {
+      w.column = 0
+      _, ok := w.dst.Write([]byte{'\n'})
+      return ok
+   }
+   return nil
+}
+
+type base64_encoder struct {
+   enc io.WriteCloser
+   wrap *wrap_writer
+}
+
+func new_base64_encoder(dst io.Writer) io.WriteCloser {
+   wrap := &wrap_writer{dst: dst, width: BASE64_WRAP}
+   return &base64_encoder{enc: base64.NewEncoder(base64.StdEncoding, wrap), wrap: wrap}
+}
+
+func (e *base64_encoder) Write(p []byte) (int, error) {
+   return e.enc.Write(p)
+}
+
+// Close writes the final, padded quantum
+func (e *base64_encoder) Close() error {
+   if ok := e.enc.Close(); ok != nil {
+      return ok
+   }
+   return e.wrap.Close()
+}
+
+// base64_decoder decodes the base64 text written to it, skipping whitespace
+// and holding back a partial quantum for the next Write
+type base64_decoder struct {
+   dst io.Writer
+   pending []byte
+   out_buf []byte
+}
+
+var err_base64_input = errors.New("invalid input")
+
+func new_base64_decoder(dst io.Writer) io.WriteCloser {
+   return &base64_decoder{dst: dst}
+}
+
+func (d *base64_decoder) Write(p []byte) (int, error) {
+   for _, ch := range p {
+      switch ch {
+      case ' ', '\t', '\n', '\v', '\f', '\r':
+      default:
+         d.pending = append(d.pending, ch)
+      }
+   }
+
+   whole := len(d.pending) / 4 * 4
+   if whole == 0 {
+      return len(p), nil
+   }
+
+   if need := base64.StdEncoding.DecodedLen(whole); cap(d.out_buf) < need {
+      d.out_buf = make([]byte, need)
+   }
+   n_decoded, ok := base64.StdEncoding.Decode(d.out_buf[:cap(d.out_buf)], d.pending[:whole])
+   if ok != nil {
+      return 0, err_base64_input
+   }
+   d.pending = d.pending[:copy(d.pending, d.pending[whole:])]
+
+   if _, ok := d.dst.Write(d.out_buf[:n_decoded]); ok != nil {
+      return 0, ok
+   }
+   return len(p), nil
+}
+
+// Close fails on a trailing partial quantum
+func (d *base64_decoder) Close() error {
+   if len(d.pending) > 0 {
+      return err_base64_input
+   }
+   return nil
+}
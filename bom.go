@@ -0,0 +1,23 @@
+// Gotilities - cat
+// Author: prbrown
+//
+// --strip-bom, the UTF-8 byte order mark left off the start of each file.
+package main
+
+import "io"
+import "bytes"
+
+var utf8_bom = []byte{0xEF, 0xBB, 0xBF}
+
+// strip_bom drops the BOM src starts with, if it does; the bytes peeked to
+// find out are otherwise left to read
+func strip_bom(src *peek_reader) error {
+   first, ok := src.peek(len(utf8_bom))
+   if ok != nil && ok != io.EOF {
+      return ok
+   }
+   if bytes.HasPrefix(first, utf8_bom) {
+      src.discard(len(utf8_bom))
+   }
+   return nil
+}
@@ -23,16 +23,424 @@
 //                      -s, --squeeze-blank
 //                            suppress repeated empty output lines
 //
+//                      -z, --zero
+//                            lines end in NUL, not newline, for numbering,
+//                            -s, -E, --uniq, --tac, --rev, --head and --tail;
+//                            newlines are then plain characters
+//
+//                      --squeeze-all
+//                            -s, and drop the empty lines at the start and
+//                            end of the output
+//
 //                      -t    equivalent to -vT
 //
 //                      -T, --show-tabs
 //                            display TAB characters as ^I
 //
+//                      --translate=SET1:SET2
+//                            make each byte of SET1 the byte at its place in
+//                            SET2, before anything else, as tr; a set takes
+//                            ranges like a-z and the escapes \n, \t, \r,
+//                            \0, \\, \: and \-
+//
+//                      --delete=SET
+//                            drop each byte of SET, before --translate
+//
+//                      --squeeze-repeats=SET
+//                            write a run of any byte of SET as one, after
+//                            --translate, as tr -s
+//
+//                      --ascii-only[=HOW]
+//                            drop, the default, each byte past ASCII, or with
+//                            flag write it as ?
+//
+//                      --tab-string=STR
+//                            with -T, display TAB characters as STR
+//
 //                      -u    (ignored)
 //
 //                      -v, --show-nonprinting
 //                            use ^ and M- notation, except for LFD and TAB
 //
+//                      --summary
+//                            with -v, when done, list on stderr how many of
+//                            each nonprinting byte were escaped
+//
+//                      --nonprinting-style=STYLE
+//                            with -v, caret for ^ and M- notation, or hex
+//                            for \xNN
+//
+//                      --color[=WHEN]
+//                            color line numbers and escapes; WHEN is always,
+//                            the default, never or auto, for only when
+//                            writing to a terminal
+//
+//                      -V, --verbose
+//                            in error messages, name the failing call and the
+//                            errno
+//
+//                      --status-format=FORMAT
+//                            text, the default, or json to write to stderr at
+//                            the end an array of each input's name, bytes
+//                            read, status and the reason it failed
+//
+//                      -o, --output=FILE
+//                            write to FILE, created or truncated, instead of
+//                            standard output
+//
+//                      --append
+//                            with -o, append to FILE instead of truncating it
+//
+//                      --checkpoint=PATH
+//                            with -o, record in PATH every second how many
+//                            bytes of FILE are safely written
+//
+//                      --resume
+//                            with --checkpoint, carry on a copy of one FILE
+//                            from the bytes PATH records, by --start-offset
+//                            and --append; both refuse the options that
+//                            change what is written
+//
+//                      --trim-head-bytes=N, --trim-tail-bytes=N
+//                            leave off the first, or last, N bytes of the
+//                            whole output; the last N are held in memory
+//
+//                      --chunk-lines=N
+//                            write each N output lines to a file of their
+//                            own, PRE000, PRE001 and so on, as split -l
+//
+//                      --chunk-bytes=SIZE
+//                            write each SIZE bytes of output to a file of
+//                            their own, as split -b; lines may be split
+//
+//                      --output-prefix=PRE
+//                            with --chunk-lines or --chunk-bytes, the start of each file's
+//                            name; the -o FILE when not given, else x
+//
+//                      --tac
+//                            write each file's lines in reverse order
+//
+//                      --merge
+//                            write the lines of the files, each sorted
+//                            already, as one sorted run, as sort -m
+//
+//                      --reverse, --numeric
+//                            with --merge, lines sorted largest first, and
+//                            by the number they start with
+//
+//                      --join
+//                            write a line for each pair of lines of the two
+//                            files, sorted on the join field, that share it,
+//                            as join; fields are split at --delimiter, or
+//                            at blanks
+//
+//                      --join-field=N
+//                            with --join, field N (1) is the one shared
+//
+//                      --join-unpaired=FILENUM
+//                            with --join, also write the lines of file 1,
+//                            or 2, without a pair, as join -a
+//
+//                      --shuffle
+//                            write each file's lines in a random order, as
+//                            shuf; the whole file is held in memory
+//
+//                      --random-seed=N
+//                            with --shuffle, the same order for the same N
+//
+//                      --rev
+//                            reverse the characters of each line
+//
+//                      --head=N, --head-bytes=N
+//                            write only the first N lines, or bytes, of each
+//                            file
+//
+//                      --tail=N, --tail-bytes=N
+//                            write only the last N lines, or bytes, of each
+//                            file
+//
+//                      --count
+//                            print the line, word and byte counts of each
+//                            file instead of its contents, and of more than
+//                            one a total
+//
+//                      --seq=FIRST:STEP:LAST
+//                            write the numbers from FIRST to LAST by STEP,
+//                            which may be negative or have a fraction,
+//                            rather than the files, as seq; LAST alone and
+//                            FIRST:LAST step from 1 by 1
+//
+//                      --seq-format=FORMAT
+//                            with --seq, numbers ln (left), rn (right) or rz
+//                            (zero padded), in the width of FIRST or LAST
+//
+//                      --yes[=STRING]
+//                            write STRING, y by default, as a line over and
+//                            over rather than the files, as yes, until
+//                            --max-bytes or a signal stops it
+//
+//                      --count-only
+//                            number the lines, -n or with -b the nonempty
+//                            ones, and write only how many there were
+//
+//                      --cksum
+//                            print the POSIX cksum CRC and byte count of each
+//                            file instead of its contents, and as cksum no
+//                            name for standard input
+//
+//                      --digest=ALGORITHM
+//                            print the md5, sha1 or sha256 digest of each
+//                            file instead of its contents
+//
+//                      --check=FILE
+//                            read md5, sha1 or sha256 digests and file names
+//                            from FILE, as --digest prints them, and check
+//                            each file against its digest
+//
+//                      --list-files, --check-only
+//                            open each FILE without reading it and print
+//                            whether it is readable, a directory or missing
+//
+//                      --number-style=STYLE
+//                            number a (all lines), t (nonempty lines) or
+//                            n (no lines), as in nl
+//
+//                      --number-format=FORMAT
+//                            line numbers ln (left justified), rn (right
+//                            justified) or rz (right justified, zero padded)
+//
+//                      --line-buffered[=yes|no]
+//                            write each line as soon as it is complete; on by
+//                            default when writing to a terminal
+//
+//                      --unbuffered
+//                            write output as soon as it is produced, not
+//                            waiting for a whole line or block
+//
+//                      --headers
+//                            write a ==> FILE <== line before each file, as
+//                            head does with several files
+//
+//                      --ensure-final-newline
+//                            end each file's output with a newline, adding
+//                            one where the file lacks it
+//
+//                      --strip-cr
+//                            drop the CR of CRLF line endings
+//
+//                      --line-ending=STYLE
+//                            write every line ending, LF, CRLF or a lone CR,
+//                            as lf or crlf
+//
+//                      --trim-trailing
+//                            drop spaces and tabs at the end of each line
+//
+//                      --collapse-whitespace
+//                            drop the spaces and tabs at the start and end
+//                            of each line, and make each run of them within
+//                            it one space
+//
+//                      --prefix=STR, --suffix=STR
+//                            write STR at the start, after any number, or
+//                            at the end, before any $, of each line
+//
+//                      --prefix-before-number
+//                            write the --prefix before the line number
+//
+//                      --json
+//                            write each line as a JSON object of its own,
+//                            {"n":NUM,"line":"LINE"}, n only when numbered
+//
+//                      --fields=LIST
+//                            write only the fields of each line in LIST, as
+//                            cut -f, as 1,3-5; lines without a delimiter are
+//                            written whole
+//
+//                      --delimiter=CHAR
+//                            with --fields, fields end in CHAR, not TAB
+//
+//                      --only-delimited
+//                            with --fields, leave out lines without a
+//                            delimiter
+//
+//                      --chars=LIST, --bytes=LIST
+//                            write only the characters, UTF-8 sequences, or
+//                            bytes of each line in LIST, as cut -c and -b
+//
+//                      --match=REGEXP
+//                            write only the lines matching REGEXP, in Go
+//                            regexp syntax
+//
+//                      --invert-match
+//                            with --match, write the lines not matching
+//
+//                      --number-original
+//                            with --match, number lines by their place in the
+//                            input rather than in the output
+//
+//                      --number-matching=REGEXP
+//                            number only the lines matching REGEXP, as nl -b
+//                            pREGEXP; implies -n
+//
+//                      --number-skipped
+//                            with --number-matching, let the lines not
+//                            numbered use up their numbers
+//
+//                      -R, --recursive
+//                            read the regular files under each directory
+//                            FILE, in sorted order, each with a --headers
+//                            banner when there are several
+//
+//                      -L, --dereference
+//                            with -R, follow symbolic links met in
+//                            directories, which are otherwise passed over
+//
+//                      --exclude=PATTERN
+//                            with -R, pass over files and directories whose
+//                            name matches the shell PATTERN, or whose path
+//                            does for a PATTERN with a /; may be repeated
+//
+//                      --files-from=LIST
+//                            read the FILEs named in LIST, one to a line, or
+//                            in standard input for -, in its place among the
+//                            FILEs
+//
+//                      -0, --null
+//                            with --files-from, names end in NUL rather than
+//                            newline
+//
+//                      --fd=N
+//                            read the already open file descriptor N, in its
+//                            place among the FILEs
+//
+//                      --start-offset=N
+//                            skip the first N bytes of each file
+//
+//                            N for this, --max-bytes and --block-size may
+//                            end in K, M or G for KiB, MiB or GiB, or in KB,
+//                            MB or GB for kB, MB or GB
+//
+//                      --repeat=N
+//                            write each file N times; the files must be
+//                            able to seek
+//
+//                      --lines=START:END
+//                            write only input lines START to END, counting
+//                            across files; either may be left out
+//
+//                      --renumber
+//                            with --lines, number lines by their place in the
+//                            output rather than in the input; with --repeat,
+//                            number each time over from 1
+//
+//                      --max-bytes=N
+//                            stop after writing N bytes, counting line numbers
+//                            and escapes
+//
+//                      --device-ok
+//                            read a device FILE, as /dev/zero, that may never
+//                            end; otherwise one is refused without --max-bytes
+//
+//                      --fold=WIDTH
+//                            wrap lines longer than WIDTH columns
+//
+//                      --fold-spaces
+//                            with --fold, break lines at blanks
+//
+//                      --fold-bytes
+//                            with --fold, count bytes rather than columns
+//
+//                      --wrap=WIDTH
+//                            fill lines with the words of each paragraph,
+//                            up to WIDTH columns, as fmt
+//
+//                      --expand
+//                            convert tabs to spaces
+//
+//                      --unexpand
+//                            convert leading blanks to tabs
+//
+//                      --all-blanks
+//                            with --unexpand, convert all blanks
+//
+//                      --tabs=LIST
+//                            tab stops every N columns, or at the listed
+//                            columns, instead of every 8; implies --all-blanks
+//
+//                      --uniq
+//                            write each run of the same output line once
+//
+//                      --uniq-count
+//                            --uniq, each line after the length of its run
+//                            as uniq -c
+//
+//                      --base64, --base64-decode
+//                            base64 encode, or decode, the concatenated output
+//
+//                      --hexdump[=COLS]
+//                            offset, hex and ASCII dump of the concatenated
+//                            output, COLS bytes (16) to a row
+//
+//                      --skip-binary
+//                            pass over files with a NUL byte in their first
+//                            block, with a warning
+//
+//                      --strip-bom
+//                            drop the UTF-8 byte order mark, EF BB BF, that
+//                            a file starts with
+//
+//                      --progress
+//                            report the bytes read from each file, and the
+//                            percentage done, to standard error twice a second
+//                            and as the file ends
+//
+//                      --pv
+//                            the same as a bar redrawn on one line, with the
+//                            rate and, given the size, the time to go; nothing
+//                            when standard error is not a terminal
+//
+//                      --max-line-length=N
+//                            bound the input lines, at most N bytes each, that
+//                            --uniq, --rev, --fold, --json and the other line
+//                            at a time options hold in memory
+//
+//                      --on-long-line=POLICY
+//                            with --max-line-length, error, the default, to
+//                            fail on a longer line, or break to split it
+//
+//                      --replay=DURATION
+//                            write a line each DURATION, as 500ms or 2s, as
+//                            if the input were a live stream
+//
+//                      --decompress
+//                            write the uncompressed data of gzip and bzip2
+//                            files, as zcat and bzcat do
+//
+//                      --block-size=N
+//                            read and write in blocks of N bytes instead of
+//                            the size the files suggest; at most 16M
+//
+//                      --no-fionread
+//                            never ask how much input is waiting (FIONREAD)
+//                            before reading; as does GOTIL_CAT_NO_FIONREAD
+//                            set true, as 1, t or true (strconv.ParseBool)
+//
+//                      --show-io-info
+//                            write to stderr, for each file, the block sizes
+//                            and buffers it is read and written with, how
+//                            the output is buffered and whether FIONREAD is
+//                            asked
+//
+//                      --safe-scan
+//                            render a line at a time without cat's sentinel
+//                            scan, the slower reference for its output
+//
+//                      --deterministic
+//                            read and write as on any other machine, for
+//                            reproducing a bug: no FIONREAD, and blocks of
+//                            128 KiB whatever the files' st_blksize, unless
+//                            --block-size is given
+//
 //                      --help
 //                            display this help and exit
 //
@@ -40,6 +448,11 @@
 //                            output version information and exit
 //
 //                      With no FILE, or when FILE is -, read standard input.
+//                      Long options may be cut short to any prefix that is
+//                      not the start of another.
+//                      A FILE starting http:// or https:// is fetched.
+//                      After --, every argument is a FILE, even one
+//                      starting with -.
 //
 //    Examples:      cat f - g
 //                      Output f's contents, then STDIN, then g's contents.
@@ -53,45 +466,482 @@ package main
 import "os"
 import "io"
 import "fmt"
+import "errors"
+import "strings"
+import "strconv"
+import "bufio"
+import "bytes"
+import "context"
+import "regexp"
+import "path/filepath"
+import "sort"
+import "slices"
 import "syscall"
 import "math"
+import "math/rand"
+import "time"
 import "unsafe"  //for pointer conversions in syscall
 
 const IO_BLK_SIZE_DEFAULT int64 = 128*1024; // default taken from Unix cat [1]
+const IO_BLK_SIZE_MAX int64 = 16*1024*1024; // cap on st_blksize, buffers are a multiple of it
 const LINE_COUNTER_BUF_LEN int64 = 19;
 const FIONREAD_INTERNAL uintptr = 0x541B
 
-// options
-var number_nonblank bool = false
-var number bool = false
-var squeeze_blank bool = false
-var show_nonprinting bool = false
-var show_tabs bool = false
-var show_ends bool = false
-var special_flag string
+// Options selects the transformations applied to the input, one field per
+// cat flag. The zero value copies input to output unchanged.
+type Options struct {
+   NumberNonblank bool  // -b, implies numbering
+   Number bool          // -n
+   SqueezeBlank bool    // -s
+   SqueezeAll bool      // -s, and no blank lines at the start or end
+   ShowNonprinting bool // -v
+   ShowTabs bool        // -T
+   TabString string     // with ShowTabs, what a tab shows as; "" for ^I
+   NonprintingStyle string // "caret" or "" for ^X and M-X, "hex" for \xNN
+   ShowEnds bool        // -E
+   TrimTrailing bool    // drop blanks at the end of each line
+   StripCR bool         // read CRLF line endings as LF
+   Zero bool            // lines end in NUL rather than newline
+   LineEnding string    // "lf" or "crlf" to write every line ending, LF, CR
+                        // or CRLF, that way; "" to leave them
+   Color bool           // line numbers and escapes in ANSI colors
+   NumberFormat string  // "ln", "rn" or "rz" as in nl, "" for rn
+
+   // as --translate, --delete and --squeeze-repeats, "SET1:SET2" to make
+   // each byte of SET1 the byte at its place in SET2, "SET" to drop each byte
+   // of SET, and "SET" to write a run of any byte of SET as one, all before
+   // anything else; ParseArgs checks them, and an invalid one is left out
+   Translate string
+   Delete string
+   SqueezeRepeats string
+
+   // as --ascii-only, "drop" to leave out each byte past ASCII, 0x80 and
+   // up, or "flag" to write it as ?; after Translate
+   AsciiOnly string
+
+   // stop once this many bytes are written, numbers and escapes included;
+   // 0 for no limit
+   MaxBytes int64
+
+   // skip this many bytes of each input before anything else
+   StartOffset int64
+
+   // CatTo keeps writing to the other destinations after one fails
+   TeeContinue bool
+
+   // render a line at a time, each scan bounded by the line's length, in
+   // place of cat() and its sentinel; slower, but a reference to check the
+   // output of cat() against
+   SafeScan bool
+}
+
+// Stats reports what a call did. Counts cover the input consumed and the
+// output produced after transformation.
+type Stats struct {
+   BytesRead int64
+   BytesWritten int64
+   LinesNumbered int64
+   BlankLinesSqueezed int64
+}
+
+// returned by ParseArgs in place of a Config
+var ErrHelpRequested = errors.New("help requested")
+var ErrVersionRequested = errors.New("version requested")
+
+// the write that reached Options.MaxBytes, treated as a clean stop
+var err_max_bytes = errors.New("output limit reached")
 
-var use_fionread bool = true // optimization for supported OSs, reads in bytes available
+// UnknownOptionError is returned by ParseArgs for an option cat does not
+// know. Name is given without dashes; Long tells --name from -n.
+type UnknownOptionError struct {
+   Name string
+   Long bool
+}
 
-// line number buf
-var new_lines_static int = 0 // preserve new_lines tracking between cat() invocations
-var line_num_buf = []byte{' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', '0', '\t'} // prevents (s)printf number formatting
-var line_num_start_idx int = len(line_num_buf)-2
-var line_num_print_idx int = len(line_num_buf)-7
+func (e UnknownOptionError) Error() string {
+   if e.Long {
+      return fmt.Sprintf("unrecognized option '--%s'", e.Name)
+   }
+   return fmt.Sprintf("invalid option -- '%s'", e.Name)
+}
 
-func write_pending(out_buf []byte) []byte {
+// AmbiguousOptionError is returned by ParseArgs for a long option
+// abbreviated to a prefix of several; Matches are those options, sorted.
+type AmbiguousOptionError struct {
+   Name string
+   Matches []string
+}
+
+func (e AmbiguousOptionError) Error() string {
+   return fmt.Sprintf("option '--%s' is ambiguous; possibilities: '--%s'", e.Name, strings.Join(e.Matches, "' '--"))
+}
+
+// MissingArgumentError is returned by ParseArgs when an option that takes a
+// value is last on the command line.
+type MissingArgumentError struct {
+   Name string
+   Long bool
+}
+
+func (e MissingArgumentError) Error() string {
+   if e.Long {
+      return fmt.Sprintf("option '--%s' requires an argument", e.Name)
+   }
+   return fmt.Sprintf("option requires an argument -- '%s'", e.Name)
+}
+
+// Config is a parsed command line.
+type Config struct {
+   Options Options
+   Files []string // in order, "-" for standard input
+   Output string  // -o, empty for standard output
+   Append bool    // open Output for appending
+
+   // --checkpoint and --resume
+   checkpoint string
+   resume bool
+
+   // --chunk-lines and --chunk-bytes, lines or bytes to each file of
+   // output_prefix, or of Output when it is not given, or of "x", in place
+   // of the output
+   chunk_lines int64
+   chunk_bytes int64
+   output_prefix string
+
+   // --trim-head-bytes and --trim-tail-bytes
+   trim_head int64
+   trim_tail int64
+
+   // replaces plain concatenation of each file, e.g. Tac, given the byte
+   // ending each line, '\n' or with -z NUL, and the block size picked for
+   // the file; the cat options apply to its output
+   Mode func(dst io.Writer, src io.Reader, sep byte, blk_size int64) error
+
+   // replaces the output for each file with a summary line, e.g. from
+   // --count; name is as given on the command line
+   Report func(src io.Reader, name string, blk_size int64) (string, error)
+
+   // the line after the Report lines of more than one file, e.g. the total
+   // of --count; nil for none
+   ReportTotal func() string
+
+   // wraps the output of the whole invocation, e.g. for --base64, and is
+   // closed once all files are done
+   Filter func(dst io.Writer) io.WriteCloser
+
+   // --fold and its modifiers, made into Mode once all are parsed
+   fold_width int
+
+   // --shuffle and --random-seed, made into Mode once all are parsed
+   shuffle bool
+   random_seed int64
+   seeded bool
+   fold_spaces bool
+   fold_bytes bool
+
+   // --expand, --unexpand and their modifiers
+   expand bool
+   unexpand bool
+   all_blanks bool
+   tab_list []int
+
+   skip_binary bool // --skip-binary
+   strip_bom bool   // --strip-bom
+   progress bool    // --progress
+   pv bool          // --pv
+   device_ok bool   // --device-ok
+   show_io_info bool // --show-io-info
+   replay time.Duration // --replay, the interval between lines
+
+   // --max-line-length, 0 for none, and --on-long-line=break
+   max_line int64
+   break_long string
+   decompress bool  // --decompress
+   ensure_newline bool // --ensure-final-newline
+   headers bool        // --headers
+   color string        // --color, "always", "never" or "auto"
+   line_buffered string // --line-buffered, "yes", "no" or "" for terminals only
+   unbuffered bool      // --unbuffered
+   verbose bool         // -V, errors with the failing call and errno
+   last_error string    // the message of the last error printed
+
+   // --status-format=json, and each input's status for it
+   status_json bool
+   statuses []input_status
+   no_fionread bool     // --no-fionread, or GOTIL_CAT_NO_FIONREAD true
+
+   // --match and its modifiers
+   match *regexp.Regexp
+   invert_match bool
+   number_original bool
+
+   // --number-matching, and with number_skipped the lines it leaves
+   // unnumbered take up their numbers all the same
+   number_match *regexp.Regexp
+   number_skipped bool
+
+   collapse bool // --collapse-whitespace
+
+   // --prefix, --suffix and --prefix-before-number
+   prefix string
+   suffix string
+   prefix_first bool
+
+   json bool // --json
+   summary bool // --summary
+
+   // --fields, --delimiter and --only-delimited, and --chars and --bytes
+   fields []list_range
+   delimiter byte
+   only_delimited bool
+   chars []list_range
+   bytes []list_range
+
+   check string // --check, the list of digests to verify
+   list_files bool // --list-files
+   repeat int // --repeat, each file written this many times
+   count_only bool // --count-only, the count of numbered lines for output
+
+   // --seq and --seq-format, numbers written in place of the files
+   seq *seq_spec
+   seq_format string
+   yes *string // --yes, the line written in place of the files
+
+   // --merge and its modifiers
+   merge bool
+   reverse bool
+   numeric bool
+
+   // --join and its modifiers; the field delimiter is --delimiter's
+   join bool
+   join_field int
+   join_unpaired [2]bool
+   uniq bool       // --uniq, made into Filter once all are parsed
+   uniq_count bool // --uniq-count
+   block_size int64 // --block-size, for input and output in place of st_blksize
+
+   ctx context.Context // from main, cancelled on SIGINT or SIGTERM
+
+   recursive bool // -R, --recursive
+   dereference bool // -L, --dereference
+   exclude []string // --exclude, the patterns of files -R passes over
+
+   // --files-from, the list and the index in Files its names go at, and
+   // --null for NUL rather than newline separated names
+   files_from string
+   files_from_at int
+   null bool
+
+   // --fd, the descriptor of each Files entry that is one, by its index
+   fds map[int]int
+
+   // --lines and --renumber
+   lines_from int64 // 0 when not given
+   lines_to int64
+   renumber bool
+}
+
+// cat_state holds what GNU cat keeps in statics: the line number buffer and
+// the consecutive newline count. One state spans all files of an invocation
+// so numbering continues from file to file.
+type cat_state struct {
+   opts Options
+   out io.Writer
+
+   use_fionread bool // optimization for supported OSs, reads in bytes available
+
+   sep byte // the end of a line, newline or for Options.Zero NUL
+
+   escape_byte func(dst []byte, b byte) []byte // -v notation, by NonprintingStyle
+   tr *tr_table // Translate, Delete and SqueezeRepeats, nil for none
+   escape_counts *[256]int64 // --summary, of each byte escaped that is not printable
+
+   // line number buf
+   new_lines int // preserve new_lines tracking between cat() invocations
+   line_num int
+   line_num_buf []byte // prevents (s)printf number formatting
+   line_num_start_idx int
+   line_num_print_idx int
+
+   // --trim-trailing, blanks held back until the line goes on past them,
+   // and for render_line the line that does, after them
+   pending_blanks []byte
+   blanks_buf []byte
+
+   // --match, whether a line, given without its newline, is written; with
+   // number_original the lines left out still take their numbers
+   keep_line func(line []byte) bool
+   number_original bool
+
+   // --number-matching, whether a line, given without its newline, is
+   // numbered; with number_skipped the others still use up a number
+   number_line func(line []byte) bool
+   number_skipped bool
+
+   // --collapse-whitespace, and the line as it leaves
+   collapse bool
+   collapse_buf []byte
+
+   // --prefix and --suffix, written at the start and end of each line; the
+   // prefix after the line number unless prefix_first
+   prefix []byte
+   suffix []byte
+   prefix_first bool
+
+   json bool // --json, each line written as a JSON object
+
+   // --fields, --chars or --bytes, the part of a line that is kept,
+   // appended to dst; false to leave out the line. select_buf holds what it
+   // appends.
+   select_line func(dst []byte, line []byte) ([]byte, bool)
+   select_buf []byte
+
+   // --lines, the range of input lines written and the count so far
+   lines_from int64
+   lines_to int64 // 0 for no end
+   line_index int64
+
+   last_byte byte // of the output so far
+
+   line_buffered bool // write each line out once it is complete
+   unbuffered bool    // write out each chunk as soon as it is transformed
+
+   crlf_buf []byte // (--line-ending=crlf) the output of write
+
+   // --squeeze-all, whether a line with text is written yet and whether a
+   // blank line waits on more text to show it is not trailing
+   seen_text bool
+   held_blank bool
+   held_line []byte // rendered, for the line at a time filter_lines
+
+   stats Stats
+}
+
+func new_cat_state(out io.Writer, opts Options) *cat_state {
+   st := &cat_state{opts: opts, out: out, use_fionread: true, sep: '\n'}
+   if opts.Zero {
+      st.sep = 0
+   }
+   st.escape_byte = EscapeNonPrinting
+   if opts.NonprintingStyle == "hex" {
+      st.escape_byte = EscapeHex
+   }
+   st.tr, _ = new_tr_table(opts)
+   st.reset_line_num()
+   return st
+}
+
+// starts numbering over, the next line being 1
+func (st *cat_state) reset_line_num() {
+   st.line_num = 0
+   st.line_num_buf = []byte{' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', '0', '\t'}
+   st.line_num_start_idx = len(st.line_num_buf)-2
+   st.line_num_print_idx = len(st.line_num_buf)-7
+}
+
+// numbering is requested by either -n or -b
+func (st *cat_state) number() bool {
+   return st.opts.Number || st.opts.NumberNonblank
+}
+
+func (st *cat_state) transforms() bool {
+   return st.number() || st.opts.ShowEnds || st.opts.ShowNonprinting || st.opts.ShowTabs || st.opts.SqueezeBlank || st.opts.SqueezeAll || st.opts.TrimTrailing || st.opts.StripCR || st.opts.LineEnding != "" || st.filters()
+}
+
+// all output goes through here so that it is counted
+func (st *cat_state) write(b []byte) (int, error) {
+   // (--line-ending=crlf) callers see the count of their own bytes
+   n_given := len(b)
+   if st.opts.LineEnding == "crlf" {
+      st.crlf_buf = to_crlf(st.crlf_buf[:0], b)
+      b = st.crlf_buf
+   }
+
+   var limited bool
+   if st.opts.MaxBytes > 0 {
+      if room := st.opts.MaxBytes - st.stats.BytesWritten; int64(len(b)) > room {
+         b = b[:room]
+         limited = true
+      }
+   }
+
+   n_written, ok := retry_write(st.out, b)
+   st.stats.BytesWritten += int64(n_written)
+   if n_written > 0 {
+      st.last_byte = b[n_written-1]
+   }
+   if ok == nil && n_written < len(b) {
+      ok = io.ErrShortWrite // a writer going against the io.Writer contract
+   }
+   if ok == nil && limited {
+      ok = err_max_bytes
+   }
+   if ok == nil || n_written > n_given {
+      n_written = n_given
+   }
+   return n_written, ok
+}
+
+// end_line writes a newline as if it were input, so -E and numbering treat
+// the line it ends like any other
+func (st *cat_state) end_line() error {
+   if !st.transforms() {
+      _, ok := st.write([]byte{st.sep})
+      return ok
+   }
+   out_buf := st.transform([]byte{st.sep, st.sep}, nil) // newline and sentinel
+   _, ok := st.write_pending(out_buf)
+   return ok
+}
+
+// state_writer is the io.Writer of st.write, for output that bypasses the
+// transform yet counts as written
+type state_writer struct {
+   st *cat_state
+}
+
+func (w state_writer) Write(b []byte) (int, error) {
+   return w.st.write(b)
+}
+
+// whether Options.MaxBytes are written
+func (st *cat_state) limit_reached() bool {
+   return st.opts.MaxBytes > 0 && st.stats.BytesWritten >= st.opts.MaxBytes
+}
+
+func (st *cat_state) write_pending(out_buf []byte) ([]byte, error) {
    if len(out_buf) > 0 {
-      n_written, ok := os.Stdout.Write(out_buf);
-      if ok != nil || n_written != len(out_buf) {
-         panic("write error")
+      n_written, ok := st.write(out_buf);
+      if ok != nil {
+         return out_buf, ok
+      }
+      if n_written != len(out_buf) {
+         return out_buf, io.ErrShortWrite
       }
       out_buf = out_buf[:0] // len back to 0
-      return out_buf
+      return out_buf, nil
    }
-   return out_buf
+   return out_buf, nil
 }
 
-func next_line_num() {
+// the number of the line starting, 0 when --number-matching leaves it out
+func (st *cat_state) take_line_num(line []byte) int {
+   if st.number_line != nil && !st.number_line(line) {
+      if st.number_skipped {
+         st.next_line_num()
+      }
+      return 0
+   }
+   st.next_line_num()
+   return st.line_num
+}
+
+func (st *cat_state) next_line_num() {
+   st.line_num = st.line_num + 1
+   st.stats.LinesNumbered++
+
    // line_num_end = last digit, or line_num_buf[]
+   line_num_buf := st.line_num_buf
    line_num_buf_len := len(line_num_buf)
    end_idx := line_num_buf_len-2
 
@@ -103,178 +953,343 @@ func next_line_num() {
       line_num_buf[end_idx] = '0'
       end_idx = end_idx - 1
 
-      if (end_idx < line_num_start_idx) {
+      if (end_idx < st.line_num_start_idx) {
          break
       }
    }
 
-   if line_num_start_idx > 0 {
-      line_num_start_idx = line_num_start_idx - 1;
-      line_num_buf[line_num_start_idx] = '1'
+   if st.line_num_start_idx > 0 {
+      st.line_num_start_idx = st.line_num_start_idx - 1;
+      line_num_buf[st.line_num_start_idx] = '1'
    } else {
       line_num_buf[0] = '>'
    }
 
-   if (line_num_start_idx < line_num_print_idx) {
-      line_num_print_idx = line_num_print_idx - 1
+   if (st.line_num_start_idx < st.line_num_print_idx) {
+      st.line_num_print_idx = st.line_num_print_idx - 1
    }
 }
 
-func cat(f *os.File, in_buf []byte, in_size int64, out_buf []byte, out_size int64) bool {
-   var new_lines int = new_lines_static // number of consecutive new_lines in input
-   var ch byte
+// appends the current line number and its tab in the NumberFormat layout,
+// at least 6 wide
+func (st *cat_state) append_line_num(out_buf []byte) []byte {
+   if !st.opts.Color {
+      return st.append_number(out_buf)
+   }
+   out_buf = append(out_buf, ANSI_NUMBER...)
+   out_buf = st.append_number(out_buf)
+   return append(out_buf, ANSI_RESET...)
+}
 
-   for ;; {
-      for ;; {
-         cur_out_len := int64(len(out_buf)) // current amount of bytes, not capacity
-         // write if there are >= out_size bytes in out_buf
-         if (cur_out_len >= out_size) {
-
-            remaining_bytes := cur_out_len;
-            start := cur_out_len-remaining_bytes // initially 0
-            for ;; {
-               n_written, ok := os.Stdout.Write(out_buf[start:start+out_size]);
-               if ok != nil {
-                  fmt.Fprintln(os.Stderr, "cat: ", ok)
-                  return false
-               }
-               if int64(n_written) != out_size {
-                  panic("write error")
-               }
+func (st *cat_state) append_number(out_buf []byte) []byte {
+   line_num := st.line_num_buf[st.line_num_print_idx:] // right justified
 
-               remaining_bytes -= out_size
-               start += out_size
+   switch st.opts.NumberFormat {
+   case "ln":
+      digits := st.line_num_buf[st.line_num_start_idx:len(st.line_num_buf)-1]
+      out_buf = append(out_buf, digits...)
+      for i := len(digits); i < len(line_num)-1; i++ {
+         out_buf = append(out_buf, ' ')
+      }
+      return append(out_buf, '\t')
+   case "rz":
+      for _, ch := range line_num {
+         if ch == ' ' {
+            ch = '0'
+         }
+         out_buf = append(out_buf, ch)
+      }
+      return out_buf
+   }
+   return append(out_buf, line_num...)
+}
 
-               if remaining_bytes < out_size {
-                  break;
-               }
-            }
+// files that support the FIONREAD ioctl
+type fd_reader interface {
+   io.Reader
+   Fd() uintptr
+   Name() string
+}
 
-            // move any remaining bytes to beginning of buffer
-            if remaining_bytes > 0 {
-               byte_slice := out_buf[start:start+remaining_bytes]
-               copy(out_buf, byte_slice)
-            }
+// EscapeNonPrinting appends b to dst in the notation of -v: control bytes as
+// ^X, DEL as ^?, and bytes above 0x7F as M- followed by the notation of the
+// low seven bits. TAB and LFD are escaped too; callers that keep them raw must
+// check for them first.
+func EscapeNonPrinting(dst []byte, b byte) []byte {
+   if b >= ' ' {
+      if b < 0x7F { // valid ASCII code
+         return append(dst, b)
+      } else if b == 0x7F { // DEL character
+         return append(dst, '^', '?')
+      }
 
-            // update length of slice
-            out_buf = out_buf[:remaining_bytes]
+      dst = append(dst, 'M', '-')
+      if b >= 128 + ' ' {
+         if b < 128 + 127 {
+            return append(dst, b-128)
          }
+         return append(dst, '^', '?')
+      }
+      return append(dst, '^', b-128+64)
+   }
+   return append(dst, '^', b + 64)
+}
 
-         // inbuf empty?
-         if len(in_buf) == 0 {
+// EscapeHex is EscapeNonPrinting with each byte it would escape written as
+// \xNN instead.
+func EscapeHex(dst []byte, b byte) []byte {
+   const digits = "0123456789abcdef"
+   if b >= ' ' && b < 0x7F {
+      return append(dst, b)
+   }
+   return append(dst, '\\', 'x', digits[b>>4], digits[b&0xF])
+}
 
-            var n_to_read uint
-
-            if use_fionread {
-               if r, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), FIONREAD_INTERNAL, uintptr(unsafe.Pointer(&n_to_read))); r < 0 {
-                  if errno == syscall.EOPNOTSUPP || errno == syscall.ENOTTY || errno == syscall.EINVAL || errno == syscall.ENODEV || errno == syscall.ENOSYS {
-                     use_fionread = false; // error code indicates no FIONREAD support for file type
-                  } else {
-                     fmt.Println(os.Stderr, "cat : cannot do ioctl on ", f.Name())
-                     new_lines_static = new_lines
-                     return false;
-                  }
-               }
+func (st *cat_state) cat(f io.Reader, in_buf []byte, in_size int64, out_buf []byte, out_size int64) error {
+   fd_f, is_fd := f.(fd_reader)
+
+   for ;; {
+      cur_out_len := int64(len(out_buf)) // current amount of bytes, not capacity
+      // write if there are >= out_size bytes in out_buf
+      if (cur_out_len >= out_size) {
+
+         remaining_bytes := cur_out_len;
+         start := cur_out_len-remaining_bytes // initially 0
+         for ;; {
+            n_written, ok := st.write(out_buf[start:start+out_size]);
+            if ok != nil {
+               return ok
+            }
+            if int64(n_written) != out_size {
+               return io.ErrShortWrite
             }
 
-            if n_to_read == 0 {
-               out_buf = write_pending(out_buf)
+            remaining_bytes -= out_size
+            start += out_size
+
+            if remaining_bytes < out_size {
+               break;
             }
+         }
+
+         // move any remaining bytes to beginning of buffer
+         if remaining_bytes > 0 {
+            byte_slice := out_buf[start:start+remaining_bytes]
+            copy(out_buf, byte_slice)
+         }
+
+         // update length of slice
+         out_buf = out_buf[:remaining_bytes]
+      }
+
+      var n_to_read uint
 
-            // read more input into in_buf
-            // Read() only reads len(in_buf), which is 0 inside this conditional
-            // change slice length to its full capacity -1 for the Read() call
-            in_buf_full_cap := in_buf[:cap(in_buf)-1] // leave room for sentinel
-            n_read, ok := f.Read(in_buf_full_cap)
-            if ok != nil && ok != io.EOF {
-               fmt.Fprintln(os.Stderr, "cat: ", ok)
-               //write_pending(out_buf, remaining_bytes)
-               out_buf = write_pending(out_buf)
-               new_lines_static = new_lines
-               return false
+      if st.use_fionread && is_fd {
+         if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd_f.Fd(), FIONREAD_INTERNAL, uintptr(unsafe.Pointer(&n_to_read))); errno != 0 {
+            if errno == syscall.EOPNOTSUPP || errno == syscall.ENOTTY || errno == syscall.EINVAL || errno == syscall.ENODEV || errno == syscall.ENOSYS {
+               st.use_fionread = false; // error code indicates no FIONREAD support for file type
+            } else {
+               return fmt.Errorf("cannot do ioctl on %s", fd_f.Name())
             }
+         }
+      }
+
+      if n_to_read == 0 {
+         var ok error
+         if out_buf, ok = st.write_pending(out_buf); ok != nil {
+            return ok
+         }
+      }
+
+      // read more input into in_buf, all of its capacity but the last slot,
+      // which is kept for the sentinel
+      in_buf = in_buf[:cap(in_buf)]
+      n_read, ok := retry_read(f, in_buf[:len(in_buf)-1])
+      st.stats.BytesRead += int64(n_read)
+
+      // bytes read + sentinel, set in place so in_buf is never grown and
+      // reallocated under out_buf's sizing. They are written even when the
+      // same read ended the input or failed, which is seen to after them.
+      if n_read > 0 {
+         in_buf[n_read] = st.sep
+         out_buf = st.transform(in_buf[:n_read+1], out_buf)
+      }
+      if ok != nil {
+         _, write_ok := st.write_pending(out_buf)
+         if ok == io.EOF {
+            return write_ok
+         }
+         return ok // the read error is the one to report
+      }
 
-            if n_read == 0 {
-               out_buf = write_pending(out_buf)
-               new_lines_static = new_lines
-               return true
+      // (--unbuffered) write it all now, (--line-buffered) the complete lines
+      if st.unbuffered {
+         if out_buf, ok = st.write_pending(out_buf); ok != nil {
+            return ok
+         }
+      } else if st.line_buffered {
+         if end := bytes.LastIndexByte(out_buf, st.sep)+1; end > 0 {
+            if _, ok := st.write(out_buf[:end]); ok != nil {
+               return ok
             }
+            out_buf = out_buf[:copy(out_buf, out_buf[end:])]
+         }
+      }
+   }
+}
 
-            // change len(in_buf) to include bytes read + sentinel
-            in_buf = in_buf[:n_read]
-            in_buf = append(in_buf, '\n') // sentinel
-         } else {
-            new_lines = new_lines+1
-            if new_lines > 0 {
-               if new_lines >= 2 {
-                  new_lines = 2 // limit counter from wrapping
-
-                  // (-s) option to substitute multiple new_lines with single newline
-                  if squeeze_blank {
-                     ch = in_buf[0]
-                     in_buf = in_buf[1:]
-                     continue
-                  }
+// writes out the blanks held back by --trim-trailing, the line having gone
+// on past them
+func (st *cat_state) flush_blanks(out_buf []byte) []byte {
+   for _, ch := range st.pending_blanks {
+      if ch == '\t' && st.opts.ShowTabs {
+         out_buf = st.escape(out_buf, ch)
+      } else {
+         out_buf = append(out_buf, ch)
+      }
+   }
+   st.pending_blanks = st.pending_blanks[:0]
+   return out_buf
+}
+
+// transform renders one chunk of input and appends the result to out_buf.
+// in_buf holds the bytes read followed by a '\n' sentinel, which ends the
+// scan without a bounds check per byte. new_lines is saved in the state so
+// lines and blank runs carry on into the next chunk.
+func (st *cat_state) transform(in_buf []byte, out_buf []byte) []byte {
+   var new_lines int = st.new_lines // number of consecutive new_lines in input
+   var ch byte
+   number := st.number()
+   number_nonblank := st.opts.NumberNonblank
+   squeeze_all := st.opts.SqueezeAll
+   squeeze_blank := st.opts.SqueezeBlank || squeeze_all
+   show_nonprinting := st.opts.ShowNonprinting
+   show_tabs := st.opts.ShowTabs
+   show_ends := st.opts.ShowEnds
+   trim_trailing := st.opts.TrimTrailing
+   styled := st.opts.Color || st.opts.NonprintingStyle == "hex" || st.escape_counts != nil // st.escape, not EscapeNonPrinting
+   sep := st.sep // ends each line, newline or for -z NUL
+
+   ch = in_buf[0];
+   in_buf = in_buf[1:]
+
+   for ;; {
+      for ch == sep {
+         // the sentinel, chunk done
+         if len(in_buf) == 0 {
+            st.new_lines = new_lines
+            return out_buf
+         }
+
+         // (--trim-trailing) the held back blanks ended the line
+         st.pending_blanks = st.pending_blanks[:0]
+
+         new_lines = new_lines+1
+         if new_lines > 0 {
+            if new_lines >= 2 {
+               new_lines = 2 // limit counter from wrapping
+
+               // (-s) option to substitute multiple new_lines with single newline
+               if squeeze_blank {
+                  st.stats.BlankLinesSqueezed++
+                  ch = in_buf[0]
+                  in_buf = in_buf[1:]
+                  continue
                }
+            }
 
-               // (-n) line numbers on empty lines?
-               if number && !number_nonblank {
-                  next_line_num()
-                  out_buf = append(out_buf, line_num_buf[line_num_print_idx:]...)
+            // (--squeeze-all) blank lines before the first text go, the
+            // others wait for more text
+            if squeeze_all {
+               if st.seen_text {
+                  st.held_blank = true
+               } else {
+                  st.stats.BlankLinesSqueezed++
                }
+               ch = in_buf[0]
+               in_buf = in_buf[1:]
+               continue
             }
 
-            // (-e) tack on $ for show ends option
-            if show_ends {
-               out_buf = append(out_buf, '$')
+            // (-n) line numbers on empty lines?
+            if number && !number_nonblank {
+               st.next_line_num()
+               out_buf = st.append_line_num(out_buf)
             }
+         }
 
-            // newline
-            out_buf = append(out_buf, '\n')
+         // (-e) tack on $ for show ends option
+         if show_ends {
+            out_buf = append(out_buf, '$')
          }
 
+         // newline
+         out_buf = append(out_buf, sep)
+
          ch = in_buf[0];
          in_buf = in_buf[1:]
+      }
 
-         if (ch != '\n') {
-            break
+      // (--trim-trailing) a line of nothing but blanks counts as empty
+      if trim_trailing && new_lines >= 0 {
+         for ch == ' ' || ch == '\t' {
+            st.pending_blanks = append(st.pending_blanks, ch)
+            ch = in_buf[0]
+            in_buf = in_buf[1:]
+         }
+         if ch == sep {
+            continue
+         }
+      }
+
+      // (--squeeze-all) text follows the held blank line, write it
+      if st.held_blank {
+         st.held_blank = false
+         if number && !number_nonblank {
+            st.next_line_num()
+            out_buf = st.append_line_num(out_buf)
          }
+         if show_ends {
+            out_buf = append(out_buf, '$')
+         }
+         out_buf = append(out_buf, sep)
       }
+      st.seen_text = true
 
       // beginning of a line + line numbers are requested
       if new_lines >= 0 && number {
-         next_line_num();
-         out_buf = append(out_buf, line_num_buf[line_num_print_idx:]...)
+         st.next_line_num();
+         out_buf = st.append_line_num(out_buf)
       }
 
       // loop until newline found (buffer empty or actual newline found)
       if show_nonprinting {
          // convert non-printing characters
          for ;; {
-            if ch >= ' ' {
-               if ch < 0x7F { // valid ASCII code
-                  out_buf = append(out_buf, ch)
-               } else if ch == 0x7F { // DEL character
-                  out_buf = append(out_buf, '^', '?')
+            if trim_trailing {
+               if ch == ' ' || ch == '\t' {
+                  st.pending_blanks = append(st.pending_blanks, ch)
+                  ch = in_buf[0]
+                  in_buf = in_buf[1:]
+                  continue
+               } else if ch != sep {
+                  out_buf = st.flush_blanks(out_buf)
+               }
+            }
+
+            if ch == '\t' {
+               if show_tabs {
+                  out_buf = st.escape(out_buf, ch)
                } else {
-                  out_buf = append(out_buf, 'M', '-')
-                  if ch >= 128 + ' ' {
-                     if ch < 128 + 127 {
-                        out_buf = append(out_buf, ch-128)
-                     } else {
-                        out_buf = append(out_buf, '^', '?')
-                     }
-                  } else {
-                     out_buf = append(out_buf, '^', ch-128+64)
-                  }
+                  out_buf = append(out_buf, '\t')
                }
-            } else if ch == '\t' && !show_tabs {
-               out_buf = append(out_buf, '\t')
-            } else if ch == '\n' {
+            } else if ch == sep {
                new_lines = -1
                break
+            } else if styled {
+               out_buf = st.escape(out_buf, ch)
             } else {
-               out_buf = append(out_buf, '^', ch + 64)
+               out_buf = EscapeNonPrinting(out_buf, ch)
             }
 
             ch = in_buf[0]
@@ -282,9 +1297,20 @@ func cat(f *os.File, in_buf []byte, in_size int64, out_buf []byte, out_size int6
          }
       } else {
          for ;; {
+            if trim_trailing {
+               if ch == ' ' || ch == '\t' {
+                  st.pending_blanks = append(st.pending_blanks, ch)
+                  ch = in_buf[0]
+                  in_buf = in_buf[1:]
+                  continue
+               } else if ch != sep {
+                  out_buf = st.flush_blanks(out_buf)
+               }
+            }
+
             if ch == '\t' && show_tabs {
-               out_buf = append(out_buf, '^', ch + 64)
-            } else if ch != '\n' {
+               out_buf = st.escape(out_buf, ch)
+            } else if ch != sep {
                out_buf = append(out_buf, ch)
             } else {
                new_lines = -1
@@ -298,76 +1324,554 @@ func cat(f *os.File, in_buf []byte, in_size int64, out_buf []byte, out_size int6
    }
 }
 
-func simple_cat(f *os.File, buf []byte) bool {
+func (st *cat_state) simple_cat(f io.Reader, buf []byte) error {
    for ;; {
-      n_read, ok := f.Read(buf)
-      if ok != nil && ok != io.EOF {
-         fmt.Fprintln(os.Stderr, "cat: ", ok)
-         return false
+      n_read, ok := retry_read(f, buf)
+      st.stats.BytesRead += int64(n_read)
+
+      // the bytes read first, even when the same read ended the input or
+      // failed
+      if n_read > 0 {
+         n_written, write_ok := st.write(buf[:n_read])
+         if write_ok != nil {
+            return write_ok
+         }
+         if n_written != n_read {
+            return io.ErrShortWrite
+         }
       }
 
-      if n_read == 0 {
-         return true // EOF
+      if ok == io.EOF {
+         return nil
+      } else if ok != nil {
+         return ok
       }
+   }
+}
 
-      n_written, ok := os.Stdout.Write(buf[:n_read])
-      if ok != nil {
-         fmt.Fprintln(os.Stderr, "cat: ", ok)
+// picks simple_cat() or cat() and sizes their buffers
+func (st *cat_state) run(f io.Reader, in_size int64, out_bSize int64) error {
+   var ret error
+
+   f = new_cr_reader(new_tr_reader(f, st), st.opts)
+
+   if st.filters() || st.opts.SafeScan && st.transforms() {
+      return st.filter_lines(f, in_size, out_bSize)
+   }
+
+   if !st.transforms() {
+      buf := make([]byte, in_size)
+      ret = st.simple_cat(f, buf)
+      buf = nil
+   } else {
+      in_cap, out_cap := st.buffer_sizes(in_size, out_bSize)
+      in_buf := make([]byte, 0, in_cap)
+      out_buf := make([]byte, 0, out_cap)
+      ret = st.cat(f, in_buf, in_size, out_buf, out_bSize)
+      in_buf = nil
+      out_buf = nil
+   }
+
+   return ret
+}
+
+// run_mode runs a Config.Mode over f, piping its output through the
+// transform when there is one.
+func (st *cat_state) run_mode(mode func(io.Writer, io.Reader, byte, int64) error, f io.Reader, in_size int64, out_bSize int64) error {
+   if !st.transforms() && st.tr == nil {
+      return mode(state_writer{st}, f, st.sep, in_size)
+   }
+
+   pr, pw := io.Pipe()
+   done := make(chan struct{})
+   go func() {
+      pw.CloseWithError(mode(pw, f, st.sep, in_size))
+      close(done)
+   }()
+
+   ok := st.run(pr, in_size, out_bSize)
+   pr.Close() // unblocks the mode if the transform stopped early
+   <-done
+   return ok
+}
+
+// render_line applies the options to one input line, given without its
+// newline, and appends the result to dst. It returns the number given to the
+// line (0 if none) and false when -s squeezes the line away. It is the line
+// at a time counterpart of cat() and shares its state. With SqueezeAll a
+// blank line after text is still returned; the caller holds it back until
+// a line with text follows.
+func (st *cat_state) render_line(dst []byte, line []byte, has_nl bool) ([]byte, int, bool) {
+   num := 0
+
+   if len(st.pending_blanks) > 0 {
+      st.blanks_buf = append(append(st.blanks_buf[:0], st.pending_blanks...), line...)
+      line = st.blanks_buf
+      st.pending_blanks = st.pending_blanks[:0]
+   }
+   if st.opts.TrimTrailing {
+      trimmed := bytes.TrimRight(line, " \t")
+      if !has_nl {
+         // held as cat() holds them, for the next file may carry the line on
+         st.pending_blanks = append(st.pending_blanks, line[len(trimmed):]...)
+         if len(trimmed) == 0 {
+            return dst, 0, false
+         }
+      }
+      line = trimmed
+   }
+   if st.collapse {
+      st.collapse_buf = collapse_blanks(st.collapse_buf[:0], line)
+      line = st.collapse_buf
+   }
+
+   if len(line) == 0 {
+      // blank line, see the new_lines handling in cat()
+      st.new_lines = st.new_lines+1
+      if st.new_lines > 0 {
+         if st.new_lines >= 2 {
+            st.new_lines = 2
+            if st.opts.SqueezeBlank || st.opts.SqueezeAll {
+               st.stats.BlankLinesSqueezed++
+               return dst, 0, false
+            }
+         }
+         if st.opts.SqueezeAll && !st.seen_text {
+            st.stats.BlankLinesSqueezed++
+            return dst, 0, false
+         }
+         if st.number() && !st.opts.NumberNonblank {
+            num = st.take_line_num(line)
+         }
+      }
+      dst = append(dst, st.suffix...)
+      if st.opts.ShowEnds {
+         dst = append(dst, '$')
+      }
+      return dst, num, true
+   }
+
+   if st.new_lines >= 0 && st.number() {
+      num = st.take_line_num(line)
+   }
+   st.seen_text = true
+
+   for _, ch := range line {
+      if st.opts.ShowNonprinting {
+         if ch == '\t' && !st.opts.ShowTabs {
+            dst = append(dst, '\t')
+         } else {
+            dst = st.escape(dst, ch)
+         }
+      } else if ch == '\t' && st.opts.ShowTabs {
+         dst = st.escape(dst, ch)
+      } else {
+         dst = append(dst, ch)
+      }
+   }
+
+   dst = append(dst, st.suffix...)
+   if has_nl {
+      if st.opts.ShowEnds {
+         dst = append(dst, '$')
+      }
+      st.new_lines = 0
+   } else {
+      st.new_lines = -1 // line continues into the next file
+   }
+
+   return dst, num, true
+}
+
+// Cat copies src to dst applying opts, as the cat command does for a single
+// input.
+func Cat(dst io.Writer, src io.Reader, opts Options) (Stats, error) {
+   return CatContext(context.Background(), dst, src, opts)
+}
+
+// CatContext is Cat with cancellation. ctx is checked before every read of
+// src, so a blocked read is not interrupted.
+func CatContext(ctx context.Context, dst io.Writer, src io.Reader, opts Options) (Stats, error) {
+   st := new_cat_state(dst, opts)
+   if ok := skip_input(src, opts.StartOffset); ok != nil {
+      return st.stats, ok
+   }
+   ok := st.run(ctx_reader{ctx, src}, IO_BLK_SIZE_DEFAULT, IO_BLK_SIZE_DEFAULT)
+   if ok == err_max_bytes {
+      ok = nil
+   }
+   return st.stats, ok
+}
+
+// stops reading once ctx is done
+type ctx_reader struct {
+   ctx context.Context
+   r io.Reader
+}
+
+func (c ctx_reader) Read(p []byte) (int, error) {
+   if ok := c.ctx.Err(); ok != nil {
+      return 0, ok
+   }
+   return c.r.Read(p)
+}
+
+// CatFunc renders src like Cat but, instead of writing the result, calls fn
+// for each output line with the transformed line minus its newline. lineNum
+// is the line's number, or 0 when the line is not numbered. An error from fn
+// stops the scan and is returned.
+func CatFunc(src io.Reader, opts Options, fn func(lineNum int, line []byte) error) error {
+   st := new_cat_state(nil, opts)
+   if ok := skip_input(src, opts.StartOffset); ok != nil {
+      return ok
+   }
+   src = new_cr_reader(new_tr_reader(src, st), opts)
+   ls := new_line_scanner(src, IO_BLK_SIZE_DEFAULT)
+   ls.sep = st.sep
+   var line_buf []byte
+   var held_buf []byte // (SqueezeAll) the blank line waiting on text
+   held_num, held := 0, false
+
+   for ;; {
+      line, ok := ls.next()
+      if ok == io.EOF {
+         return nil
+      } else if ok != nil {
+         return ok
+      }
+
+      has_nl := line[len(line)-1] == st.sep
+      if has_nl {
+         line = line[:len(line)-1]
+      }
+
+      out, num, keep := st.render_line(line_buf[:0], line, has_nl)
+      line_buf = out
+      if !keep {
+         continue
+      }
+
+      if opts.SqueezeAll && st.new_lines > 0 {
+         held_buf = append(held_buf[:0], out...)
+         held_num, held = num, true
+         continue
+      }
+      if held {
+         held = false
+         if ok := fn(held_num, held_buf); ok != nil {
+            return ok
+         }
+      }
+
+      if ok := fn(num, out); ok != nil {
+         return ok
+      }
+   }
+}
+
+// line_scanner hands out whole input lines for the line at a time modes.
+// Lines longer than its buffer are collected in full.
+type line_scanner struct {
+   rd *bufio.Reader
+   long_buf []byte
+   sep byte // ends each line, newline unless -z
+   ok error // what came with the last line, given on the next call
+}
+
+func new_line_scanner(src io.Reader, blk_size int64) *line_scanner {
+   return &line_scanner{rd: bufio.NewReaderSize(src, int(blk_size)), sep: '\n'}
+}
+
+// next returns the next line with its newline, if it has one. The slice is
+// only valid until the following call. At the end of input it returns io.EOF.
+func (ls *line_scanner) next() ([]byte, error) {
+   if ls.ok != nil {
+      return nil, ls.ok
+   }
+   line, ok := ls.rd.ReadSlice(ls.sep)
+   if ok == bufio.ErrBufferFull {
+      // line longer than the reader buffer, collect the rest of it
+      ls.long_buf = append(ls.long_buf[:0], line...)
+      for ok == bufio.ErrBufferFull {
+         line, ok = ls.rd.ReadSlice(ls.sep)
+         ls.long_buf = append(ls.long_buf, line...)
+      }
+      line = ls.long_buf
+   }
+
+   if ok != nil && len(line) > 0 {
+      ls.ok = ok
+      return line, nil // last line without a newline, EOF or the error comes on the next call
+   } else if ok != nil {
+      return nil, ok
+   }
+   return line, nil
+}
+
+// buffer size for a file of st_blksize blksize, which can be 0 or
+// absurdly large on some filesystems and special files
+func io_blksize(blksize int64) int64 {
+   size := math.Max(float64(blksize), float64(IO_BLK_SIZE_DEFAULT))
+   return int64(math.Min(size, float64(IO_BLK_SIZE_MAX)))
+}
+
+func handle_file(cfg *Config, st *cat_state, fName string, out_stat *syscall.Stat_t, out_bSize int64) bool {
+   if is_url(fName) {
+      return handle_url(cfg, st, fName, out_bSize)
+   }
+
+   fDes, in_stat, ok := probe(fName)
+   if ok != nil {
+      print_error(cfg, ok)
+      return false
+   }
+
+   // close file upon function return, stdin stays open for later "-"
+   defer func() {
+      if fDes == os.Stdin {
+         return
+      }
+      if ok = fDes.Close(); ok != nil {
+         print_error(cfg, ok)
+      }
+   }()
+   return handle_open(cfg, st, fDes, &in_stat, fName, out_stat, out_bSize)
+}
+
+// probe opens fName, "-" being stdin, and stats it
+func probe(fName string) (*os.File, syscall.Stat_t, error) {
+   var in_stat syscall.Stat_t
+   fDes := os.Stdin
+   if fName != "-" {
+      var ok error
+      if fDes, ok = os.Open(fName); ok != nil { // os.Open() defaults to O_RDONLY permission
+         return nil, in_stat, ok
+      }
+   }
+
+   in_stat, ok := fstat(fDes)
+   if ok != nil && fDes != os.Stdin {
+      fDes.Close()
+   }
+   return fDes, in_stat, ok
+}
+
+// fstat is syscall.Fstat of an open file, failing as os calls do
+func fstat(fDes *os.File) (syscall.Stat_t, error) {
+   var in_stat syscall.Stat_t
+   if ok := syscall.Fstat(int(fDes.Fd()), &in_stat); ok != nil {
+      return in_stat, &os.PathError{Op: "fstat", Path: fDes.Name(), Err: ok}
+   }
+   return in_stat, nil
+}
+
+// handle_fd is handle_file for --fd, a descriptor already open. Like stdin,
+// descriptors 0 to 2 stay open; any other is closed once read.
+func handle_fd(cfg *Config, st *cat_state, fd int, fName string, out_stat *syscall.Stat_t, out_bSize int64) bool {
+   fDes := os.NewFile(uintptr(fd), fName)
+   if fd > 2 {
+      defer fDes.Close()
+   }
+   in_stat, ok := fstat(fDes)
+   if ok != nil {
+      print_error(cfg, ok)
+      return false
+   }
+   return handle_open(cfg, st, fDes, &in_stat, fName, out_stat, out_bSize)
+}
+
+// output_input names the input that is the regular file at the --output
+// path, if any, found before opening it for writing would empty it
+func output_input(cfg *Config) (string, bool) {
+   var out_stat syscall.Stat_t
+   if syscall.Stat(cfg.Output, &out_stat) != nil || out_stat.Mode & syscall.S_IFMT != syscall.S_IFREG {
+      return "", false
+   }
+   for i, name := range cfg.Files {
+      var in_stat syscall.Stat_t
+      var ok error
+      if fd, is_fd := cfg.fds[i]; is_fd {
+         ok = syscall.Fstat(fd, &in_stat)
+      } else if name == "-" {
+         ok = syscall.Fstat(int(os.Stdin.Fd()), &in_stat)
+      } else if is_url(name) {
+         continue
+      } else {
+         ok = syscall.Stat(name, &in_stat)
+      }
+      if ok == nil && in_stat.Dev == out_stat.Dev && in_stat.Ino == out_stat.Ino {
+         return name, true
+      }
+   }
+   return "", false
+}
+
+// handle_open writes an opened file, its block size and length from in_stat
+func handle_open(cfg *Config, st *cat_state, fDes *os.File, in_stat *syscall.Stat_t, fName string, out_stat *syscall.Stat_t, out_bSize int64) bool {
+   // copying a regular file onto itself would never reach EOF
+   if in_stat.Mode & syscall.S_IFMT == syscall.S_IFREG && in_stat.Dev == out_stat.Dev && in_stat.Ino == out_stat.Ino {
+      print_file_error(cfg, fName, "input file is output file")
+      return false
+   }
+
+   // a device such as /dev/zero would be read forever, unless so limited
+   if fDes != os.Stdin && cfg.Options.MaxBytes == 0 && !cfg.device_ok && is_device(fDes, in_stat) {
+      print_file_error(cfg, fName, "is a device; use --max-bytes")
+      return false
+   }
+
+   in_bSize := io_blksize(int64(in_stat.Blksize))
+   in_size := int64(math.Max(float64(in_bSize), float64(out_bSize)))
+   if cfg.block_size > 0 {
+      in_size = cfg.block_size
+   }
+   if cfg.show_io_info {
+      write_io_info(st, fName, in_stat, out_stat, in_size, out_bSize)
+   }
+
+   var size int64
+   if in_stat.Mode & syscall.S_IFMT == syscall.S_IFREG {
+      size = in_stat.Size
+   }
+   if cfg.repeat <= 1 {
+      return handle_input(cfg, st, fDes, fName, size, in_size, out_bSize)
+   }
+
+   // (--repeat) back to where the file started for each time after the first
+   start, ok := fDes.Seek(0, io.SeekCurrent)
+   if ok != nil {
+      print_file_error(cfg, fName, "cannot repeat an input that cannot seek")
+      return false
+   }
+   for i := 0; i < cfg.repeat; i++ {
+      if i > 0 {
+         if _, ok = fDes.Seek(start, io.SeekStart); ok != nil {
+            print_error(cfg, ok)
+            return false
+         }
+         if cfg.renumber {
+            st.reset_line_num()
+         }
+      }
+      if !handle_input(cfg, st, fDes, fName, size, in_size, out_bSize) {
+         return false
+      }
+      if st.limit_reached() || st.lines_done() || cfg.ctx != nil && cfg.ctx.Err() != nil {
+         break
+      }
+   }
+   return true
+}
+
+// write_header writes the --headers banner for a file, as head does. -n
+// numbering carries on across it, but the banner ends any run of blank lines
+// before it, so that -s squeezes none into the next file.
+func (st *cat_state) write_header(fName string, first bool) error {
+   if fName == "-" {
+      fName = "standard input"
+   }
+   header := "==> " + fName + " <==\n"
+   if !first {
+      header = "\n" + header
+   }
+   _, ok := st.write([]byte(header))
+   st.new_lines = 0
+   return ok
+}
+
+// handle_input writes one opened input, a file or otherwise, of size bytes
+// (0 when unknown) read in blocks of in_size
+func handle_input(cfg *Config, st *cat_state, src io.Reader, fName string, size int64, in_size int64, out_bSize int64) bool {
+   ok := skip_input(src, cfg.Options.StartOffset)
+   if ok != nil {
+      print_error(cfg, ok)
+      return false
+   }
+
+   if cfg.ctx != nil {
+      src = with_context(cfg.ctx, src)
+   }
+
+   if cfg.pv && is_terminal(os.Stderr) {
+      src = new_pv_reader(src, os.Stderr, fName, size)
+   } else if cfg.progress {
+      src = new_progress_reader(src, os.Stderr, fName, size)
+   }
+
+   if cfg.decompress {
+      if src, ok = decompress_reader(src, in_size); ok != nil {
+         print_file_error(cfg, fName, ok.Error())
          return false
       }
+   }
+
+   // what the (uncompressed) input starts with, peeked rather than read away
+   if cfg.strip_bom || cfg.skip_binary {
+      peeked := new_peek_reader(src, in_size)
+      src = peeked
+      if cfg.strip_bom {
+         if ok = strip_bom(peeked); ok != nil {
+            print_error(cfg, ok)
+            return false
+         }
+      }
+
+      // (--skip-binary) a NUL in the first block marks a binary file
+      if cfg.skip_binary {
+         first, peek_ok := peeked.peek(1)
+         if peek_ok != nil && peek_ok != io.EOF {
+            print_error(cfg, peek_ok)
+            return false
+         }
+         if bytes.IndexByte(first, 0) >= 0 {
+            print_file_error(cfg, fName, "binary file skipped")
+            return true
+         }
+      }
+   }
+
+   if cfg.max_line > 0 {
+      src = new_long_line_reader(src, cfg.max_line, cfg.break_long == "break", st.sep)
+   }
 
-      if n_written != n_read {
-         panic("write error")
+   if cfg.replay > 0 {
+      ctx := cfg.ctx
+      if ctx == nil {
+         ctx = context.Background()
       }
+      src = new_replay_reader(ctx, src, cfg.replay, st.sep, in_size)
    }
-}
 
-func handle_file(fName string, out_bSize int64) bool {
-   var fDes *os.File
-   var ok error
+   written := st.stats.BytesWritten
 
-   if fName[0] == '-' { // STDIN
-      fDes = os.Stdin
-      ok = nil
+   if cfg.Report != nil {
+      var line string
+      if line, ok = cfg.Report(src, fName, in_size); ok == nil {
+         _, ok = st.write([]byte(line))
+      }
+   } else if cfg.Mode != nil {
+      ok = st.run_mode(cfg.Mode, src, in_size, out_bSize)
    } else {
-      fDes, ok = os.Open(fName) // os.Open() defaults to O_RDONLY permission
+      ok = st.run(src, in_size, out_bSize)
    }
 
-   if ok != nil {
-      fmt.Fprintln(os.Stderr, "cat: ", ok)
-      return false
+   // (--ensure-final-newline) end the file's last line for it
+   if ok == nil && cfg.ensure_newline && st.stats.BytesWritten > written && st.last_byte != st.sep {
+      ok = st.end_line()
    }
 
-   // close file upon function return
-   defer func() {
-      if ok = fDes.Close(); ok != nil {
-         fmt.Fprintln(os.Stderr, "cat: ", ok)
-      }
-   }()
-
-   var in_stat syscall.Stat_t
-   if ok = syscall.Fstat(int(fDes.Fd()), &in_stat); ok != nil {
-      panic(ok)
+   if ok == err_max_bytes {
+      return true
    }
-
-   in_bSize := int64(math.Max(float64(in_stat.Blksize), float64(IO_BLK_SIZE_DEFAULT)))
-   in_size := int64(math.Max(float64(in_bSize), float64(out_bSize)))
-
-   var ret bool
-
-   if !(number || show_ends || show_nonprinting || show_tabs || squeeze_blank) {
-      buf := make([]byte, in_size)
-      ret = simple_cat(fDes, buf)
-      buf = nil
-   } else {
-      in_buf := make([]byte, 0, in_size+1)
-      out_buf := make([]byte, 0, out_bSize-1+in_size*4+LINE_COUNTER_BUF_LEN)
-      ret = cat(fDes, in_buf, in_size, out_buf, out_bSize)
-      in_buf = nil
-      out_buf = nil
+   if ok == context.Canceled {
+      return false // stopped by a signal, main has the last word
+   }
+   if ok != nil {
+      print_error(cfg, ok)
+      return false
    }
 
-   return ret;
+   return true;
 }
 
 func printUsage() {
@@ -378,155 +1882,1207 @@ func printUsage() {
               "-e                       equivalent to -vE\n" +
               "-E, --show-ends          display $ at end of each line\n" +
               "-n, --number             number all output lines\n" +
-              "-s, --squeeze-blank      suppress repeated empty output lines\n")
+              "-s, --squeeze-blank      suppress repeated empty output lines\n" +
+              "    --squeeze-all        -s, and drop empty lines at the start and end\n" +
+              "-z, --zero               lines end in NUL, not newline\n")
 
+   fmt.Printf("    --translate=SET1:SET2  make bytes of SET1 those of SET2, as tr\n" +
+              "    --delete=SET         drop the bytes of SET, as tr -d\n" +
+              "    --squeeze-repeats=SET  write runs of a byte of SET as one, as tr -s\n" +
+              "    --ascii-only[=HOW]   drop bytes past ASCII, or with flag write ?\n")
    fmt.Printf("-t                       equivalent to -vT\n" +
               "-T, --show-tabs          display TAB characters as ^I\n" +
+              "    --tab-string=STR     with -T, display TAB characters as STR\n" +
               "-u                       (ignored)\n" +
-              "-v, --show-nonprinting   use ^ and M- notation, except for LFD and TAB\n")
+              "-v, --show-nonprinting   use ^ and M- notation, except for LFD and TAB\n" +
+              "    --nonprinting-style=STYLE  with -v, caret (^X, M-X) or hex (\\xNN)\n" +
+              "    --summary            with -v, count the nonprinting bytes on stderr\n" +
+              "-V, --verbose            name the failing call and errno in error messages\n" +
+              "    --status-format=FORMAT  text, or json for a JSON array of how each\n" +
+              "                             input went, on stderr at the end\n")
+   fmt.Printf("-o, --output=FILE        write to FILE instead of standard output\n" +
+              "    --append             with -o, append to FILE rather than truncate it\n" +
+              "    --checkpoint=PATH    with -o, record the bytes written so far in PATH\n" +
+              "    --resume             with --checkpoint, carry on the copy PATH records\n" +
+              "    --trim-head-bytes=N  leave off the first N bytes of the output\n" +
+              "    --trim-tail-bytes=N  leave off the last N bytes of the output\n" +
+              "    --chunk-lines=N      write each N lines to PRE000, PRE001, ...\n" +
+              "    --chunk-bytes=SIZE   write each SIZE bytes to PRE000, PRE001, ...\n" +
+              "    --output-prefix=PRE  with --chunk-*, PRE; -o FILE if not given\n" +
+              "    --tac                write each file's lines in reverse order\n" +
+              "    --merge              merge the sorted lines of the files, as sort -m\n" +
+              "    --reverse            with --merge, the lines sorted largest first\n" +
+              "    --numeric            with --merge, lines sorted by their number\n" +
+              "    --join               join the lines of two sorted files on a field\n" +
+              "    --join-field=N       with --join, join on field N (1)\n" +
+              "    --join-unpaired=FILENUM  with --join, write the unpaired lines of file\n" +
+              "                           FILENUM too, as join -a\n" +
+              "    --shuffle            write each file's lines in a random order\n" +
+              "    --random-seed=N      with --shuffle, the seed of the order\n" +
+              "    --rev                reverse the characters of each line\n" +
+              "    --head=N             write only the first N lines of each file\n" +
+              "    --head-bytes=N       write only the first N bytes of each file\n" +
+              "    --tail=N             write only the last N lines of each file\n" +
+              "    --tail-bytes=N       write only the last N bytes of each file\n" +
+              "    --count              print line, word and byte counts of each file\n" +
+              "    --seq=FIRST:STEP:LAST  write the numbers FIRST to LAST, as seq\n" +
+              "    --seq-format=FMT     with --seq, numbers ln, rn or rz (zero padded)\n" +
+              "    --yes[=STRING]       write STRING (y) as a line until stopped, as yes\n" +
+              "    --count-only         print only the number of lines -n, or -b, numbers\n" +
+              "    --cksum              print CRC checksum and byte count of each file\n" +
+              "    --digest=ALGORITHM   print md5, sha1 or sha256 digest of each file\n" +
+              "    --check=FILE         verify the files listed with digests in FILE\n" +
+              "    --list-files         print whether each FILE can be read, reading none\n" +
+              "    --number-style=STYLE number a (all), t (nonempty) or n (no) lines\n" +
+              "    --color[=WHEN]       color line numbers and escapes: always, never, auto\n" +
+              "    --number-format=FMT  numbers ln (left), rn (right) or rz (zero padded)\n" +
+              "    --line-buffered[=yes|no]  write lines as they complete (terminals: yes)\n" +
+              "    --unbuffered         write output as soon as it is produced\n" +
+              "    --headers            write a ==> FILE <== line before each file\n" +
+              "    --ensure-final-newline  end a file lacking a final newline with one\n" +
+              "    --strip-cr           drop the CR of CRLF line endings\n" +
+              "    --line-ending=STYLE  write all line endings as lf or crlf\n" +
+              "    --trim-trailing      drop spaces and tabs at the end of each line\n" +
+              "    --collapse-whitespace  trim each line and make runs of blanks one space\n" +
+              "    --prefix=STR         start each line with STR, after its number\n" +
+              "    --suffix=STR         end each line with STR, before any $\n" +
+              "    --prefix-before-number  write the --prefix before the line number\n" +
+              "    --json               write each line as {\"n\":NUM,\"line\":\"LINE\"}\n" +
+              "    --fields=LIST        write only the fields in LIST, as cut -f\n" +
+              "    --delimiter=CHAR     with --fields, fields end in CHAR, not TAB\n" +
+              "    --only-delimited     with --fields, leave out lines without one\n" +
+              "    --chars=LIST         write only the characters in LIST, as cut -c\n" +
+              "    --bytes=LIST         write only the bytes in LIST, as cut -b\n" +
+              "    --match=REGEXP       write only the lines matching REGEXP\n" +
+              "    --invert-match       with --match, write the lines not matching\n" +
+              "    --number-original    with --match, number lines as in the input\n" +
+              "    --number-matching=REGEXP  number only the lines matching REGEXP\n" +
+              "    --number-skipped     with --number-matching, unnumbered lines use up\n" +
+              "                           their numbers\n" +
+              "-R, --recursive          read the files under each directory FILE\n" +
+              "-L, --dereference        with -R, follow symbolic links\n" +
+              "    --exclude=PATTERN    with -R, skip files matching PATTERN\n" +
+              "    --files-from=LIST    read the FILEs named in LIST, one a line\n" +
+              "-0, --null               with --files-from, names end in NUL\n" +
+              "    --fd=N               read the open file descriptor N as a FILE\n" +
+              "    --start-offset=N     skip the first N bytes of each file\n" +
+              "    --lines=START:END    write only input lines START to END\n" +
+              "    --renumber           with --lines, number lines as in the output\n" +
+              "    --repeat=N           write each file N times\n" +
+              "    --max-bytes=N        stop after writing N bytes\n" +
+              "    --device-ok          read device files without --max-bytes\n" +
+              "    --fold=WIDTH         wrap lines longer than WIDTH columns\n" +
+              "    --fold-spaces        with --fold, break lines at blanks\n" +
+              "    --fold-bytes         with --fold, count bytes rather than columns\n" +
+              "    --wrap=WIDTH         refill paragraphs to WIDTH columns, as fmt\n" +
+              "    --expand             convert tabs to spaces\n" +
+              "    --unexpand           convert leading blanks to tabs\n" +
+              "    --all-blanks         with --unexpand, convert all blanks\n" +
+              "    --tabs=LIST          tab stops every N or at the listed columns\n" +
+              "    --uniq               write each run of the same output line once\n" +
+              "    --uniq-count         --uniq, prefixing lines with their run length\n" +
+              "    --base64             base64 encode the output\n" +
+              "    --base64-decode      base64 decode the output\n" +
+              "    --hexdump[=COLS]     hex and ASCII dump of the output, COLS bytes a row\n" +
+              "    --skip-binary        skip files with a NUL byte in their first block\n" +
+              "    --strip-bom          drop the UTF-8 BOM at the start of each file\n" +
+              "    --progress           report bytes read to standard error as files go\n" +
+              "    --pv                 show progress as a bar on a terminal's standard error\n" +
+              "    --max-line-length=N  fail on an input line over N bytes\n" +
+              "    --on-long-line=POLICY  with --max-line-length, error or break the line\n" +
+              "    --replay=DURATION    write a line each DURATION, as a live stream\n" +
+              "    --block-size=N       read and write in blocks of N bytes\n" +
+              "    --no-fionread        don't check for waiting input with FIONREAD\n" +
+              "    --show-io-info       report each file's block and buffer sizes on stderr\n" +
+              "    --safe-scan          render by lines, as a check on the faster scan\n" +
+              "    --deterministic      no FIONREAD and fixed block sizes, the same anywhere\n" +
+              "    --decompress         uncompress gzip or bzip2 input\n")
    fmt.Printf("      --help     display this help and exit\n")
    fmt.Printf("      --version  output version information and exit\n")
+   fmt.Printf("\nLong options may be abbreviated to any unambiguous prefix.\n")
+   fmt.Printf("After --, every argument is a FILE, even one starting with -.\n")
+   fmt.Printf("Sizes N may end in K, M or G (powers of 1024) or KB, MB or GB (of 1000).\n")
    fmt.Printf("\n" +
             "Examples:\n" +
             "  cat f - g  Output f's contents, then standard input, then g's contents.\n" +
             "  cat        Copy standard input to standard output.\n")
 }
 
-// parses command line args for flags
-func checkForFlag(arg string) bool {
+// value of an option, attached to it or else the next argument
+func option_arg(name string, long bool, value string, attached bool, next func() (string, bool)) (string, error) {
+   if attached {
+      return value, nil
+   }
+   if value, ok := next(); ok {
+      return value, nil
+   }
+   return "", MissingArgumentError{Name: name, Long: long}
+}
+
+// the multiples of the size suffixes, K for KiB and KB for kB and so on
+var size_suffixes = []struct {
+   suffix string
+   mult int64
+}{
+   {"KB", 1000}, {"MB", 1000*1000}, {"GB", 1000*1000*1000},
+   {"K", 1024}, {"M", 1024*1024}, {"G", 1024*1024*1024},
+}
+
+// parseSize reads a byte count, N or N with a size suffix
+func parseSize(s string) (int64, error) {
+   digits, mult := s, int64(1)
+   for _, size := range size_suffixes {
+      if strings.HasSuffix(s, size.suffix) {
+         digits, mult = s[:len(s)-len(size.suffix)], size.mult
+         break
+      }
+   }
+
+   // digits only, no sign or spaces for ParseInt to allow
+   if digits == "" || strings.TrimLeft(digits, "0123456789") != "" {
+      return 0, fmt.Errorf("invalid size '%s'", s)
+   }
+   n, ok := strconv.ParseInt(digits, 10, 64)
+   if ok != nil || n > math.MaxInt64/mult {
+      return 0, fmt.Errorf("size '%s' too large", s)
+   }
+   return n*mult, nil
+}
+
+// a size option's value, from min up to max
+func size_arg(name string, value string, min int64, max int64) (int64, error) {
+   n, ok := parseSize(value)
+   if ok != nil || n < min || n > max {
+      return 0, fmt.Errorf("invalid argument '%s' for '--%s'", value, name)
+   }
+   return n, nil
+}
+
+// a count given to option name
+func count_arg(name string, value string) (int, error) {
+   n, ok := strconv.Atoi(value)
+   if ok != nil || n < 0 {
+      return 0, fmt.Errorf("invalid argument '%s' for '--%s'", value, name)
+   }
+   return n, nil
+}
+
+// the long options taking a value, and whether it is optional. An optional
+// value is only taken attached, as --name=value.
+var value_options = map[string]bool{
+   "output": false, "head": false, "head-bytes": false, "tail": false,
+   "tail-bytes": false, "number-style": false, "fold": false, "tabs": false,
+   "number-format": false, "digest": false, "check": false, "match": false,
+   "max-bytes": false, "color": true, "line-buffered": true,
+   "line-ending": false, "block-size": false, "start-offset": false,
+   "fd": false, "lines": false, "hexdump": true, "files-from": false,
+   "exclude": false, "tab-string": false, "nonprinting-style": false,
+   "repeat": false, "translate": false, "delete": false, "squeeze-repeats": false,
+   "number-matching": false, "chunk-lines": false, "chunk-bytes": false,
+   "output-prefix": false, "prefix": false, "suffix": false, "fields": false,
+   "delimiter": false, "chars": false, "bytes": false,
+   "wrap": false, "seq": false, "seq-format": false,
+   "yes": true, "ascii-only": true, "random-seed": false, "join-field": false, "join-unpaired": false,
+   "trim-head-bytes": false, "trim-tail-bytes": false, "checkpoint": false,
+   "status-format": false, "replay": false,
+   "max-line-length": false, "on-long-line": false,
+}
+
+// the long options taking no value
+var flag_options = []string{
+   "number-nonblank", "number", "squeeze-blank", "squeeze-all",
+   "trim-trailing", "skip-binary", "strip-cr", "progress", "decompress",
+   "ensure-final-newline", "headers", "verbose", "no-fionread", "unbuffered",
+   "list-files", "check-only", "invert-match", "number-original", "renumber",
+   "show-tabs", "show-ends", "show-all", "show-nonprinting", "append", "tac",
+   "rev", "count", "cksum", "fold-spaces", "fold-bytes", "expand", "unexpand",
+   "all-blanks", "base64", "base64-decode", "version", "help", "null",
+   "recursive", "dereference", "zero", "uniq", "uniq-count", "number-skipped",
+   "count-only", "strip-bom", "collapse-whitespace", "prefix-before-number",
+   "json", "only-delimited", "shuffle", "merge", "reverse", "numeric",
+   "join", "summary", "pv", "resume", "deterministic",
+   "device-ok", "safe-scan", "show-io-info",
+}
+
+// resolve_long gives the long option that name is, or abbreviates. A name
+// matching none is given back as is.
+func resolve_long(name string) (string, error) {
+   if _, takes_value := value_options[name]; takes_value || slices.Contains(flag_options, name) {
+      return name, nil
+   }
+
+   var matches []string
+   for option := range value_options {
+      if strings.HasPrefix(option, name) {
+         matches = append(matches, option)
+      }
+   }
+   for _, option := range flag_options {
+      if strings.HasPrefix(option, name) {
+         matches = append(matches, option)
+      }
+   }
+
+   switch len(matches) {
+   case 0:
+      return name, nil
+   case 1:
+      return matches[0], nil
+   }
+   sort.Strings(matches)
+   return "", AmbiguousOptionError{Name: name, Matches: matches}
+}
+
+// the short options taking a value, by the long option each stands for
+var short_value_options = map[rune]string{
+   'o': "output",
+}
+
+// set_value_option applies the long option name given value v, or for an
+// optional value not given, its default
+func set_value_option(cfg *Config, name string, v string, given bool) error {
+   opts := &cfg.Options
+   var ok error
+
+   switch name {
+      case "output":
+         cfg.Output = v
+         return nil
+      case "head", "head-bytes":
+         n, ok := count_arg(name, v)
+         byte_mode := name == "head-bytes"
+         cfg.Mode = func(dst io.Writer, src io.Reader, sep byte, blk_size int64) error {
+            return head(dst, src, n, byte_mode, sep, blk_size)
+         }
+         return ok
+      case "tail", "tail-bytes":
+         n, ok := count_arg(name, v)
+         byte_mode := name == "tail-bytes"
+         cfg.Mode = func(dst io.Writer, src io.Reader, sep byte, blk_size int64) error {
+            return tail(dst, src, n, byte_mode, sep, blk_size)
+         }
+         return ok
+      case "number-style":
+         switch v {
+         case "a":
+            opts.Number, opts.NumberNonblank = true, false
+         case "t":
+            opts.Number, opts.NumberNonblank = false, true
+         case "n":
+            opts.Number, opts.NumberNonblank = false, false
+         default:
+            return fmt.Errorf("invalid argument '%s' for '--%s'", v, name)
+         }
+         return nil
+      case "fold":
+         if cfg.fold_width, ok = count_arg(name, v); ok == nil && cfg.fold_width == 0 {
+            ok = fmt.Errorf("invalid argument '%s' for '--%s'", v, name)
+         }
+         return ok
+      case "tabs":
+         cfg.tab_list, ok = parse_tab_list(v)
+         cfg.all_blanks = true
+         return ok
+      case "number-format":
+         if v != "ln" && v != "rn" && v != "rz" {
+            return fmt.Errorf("invalid argument '%s' for '--%s'", v, name)
+         }
+         opts.NumberFormat = v
+         return nil
+      case "digest":
+         cfg.Report, ok = digest_report(v)
+         return ok
+      case "check":
+         cfg.check = v
+         return nil
+      case "match":
+         if cfg.match, ok = regexp.Compile(v); ok != nil {
+            return fmt.Errorf("invalid argument '%s' for '--%s'", v, name)
+         }
+         return nil
+      case "chunk-lines":
+         n, ok := count_arg(name, v)
+         if ok == nil && n == 0 {
+            ok = fmt.Errorf("invalid argument '%s' for '--%s'", v, name)
+         }
+         cfg.chunk_lines = int64(n)
+         return ok
+      case "trim-head-bytes":
+         cfg.trim_head, ok = size_arg(name, v, 0, math.MaxInt64)
+         return ok
+      case "trim-tail-bytes":
+         cfg.trim_tail, ok = size_arg(name, v, 0, math.MaxInt32)
+         return ok
+      case "chunk-bytes":
+         cfg.chunk_bytes, ok = size_arg(name, v, 1, math.MaxInt64)
+         return ok
+      case "seq":
+         sp, ok := parse_seq(v)
+         if ok != nil {
+            return fmt.Errorf("invalid argument '%s' for '--%s': %v", v, name, ok)
+         }
+         cfg.seq = &sp
+         return nil
+      case "join-field":
+         if cfg.join_field, ok = count_arg(name, v); ok == nil && cfg.join_field == 0 {
+            ok = fmt.Errorf("invalid argument '%s' for '--%s'", v, name)
+         }
+         return ok
+      case "join-unpaired":
+         if v != "1" && v != "2" {
+            return fmt.Errorf("invalid argument '%s' for '--%s'", v, name)
+         }
+         cfg.join_unpaired[v[0]-'1'] = true
+         return nil
+      case "random-seed":
+         if cfg.random_seed, ok = strconv.ParseInt(v, 10, 64); ok != nil {
+            return fmt.Errorf("invalid argument '%s' for '--%s'", v, name)
+         }
+         cfg.seeded = true
+         return nil
+      case "ascii-only":
+         if !given {
+            v = "drop"
+         }
+         if v != "drop" && v != "flag" {
+            return fmt.Errorf("invalid argument '%s' for '--%s'", v, name)
+         }
+         opts.AsciiOnly = v
+         return nil
+      case "yes":
+         if !given {
+            v = "y"
+         }
+         cfg.yes = &v
+         return nil
+      case "seq-format":
+         if v != "ln" && v != "rn" && v != "rz" {
+            return fmt.Errorf("invalid argument '%s' for '--%s'", v, name)
+         }
+         cfg.seq_format = v
+         return nil
+      case "wrap":
+         width, ok := count_arg(name, v)
+         if ok == nil && width == 0 {
+            ok = fmt.Errorf("invalid argument '%s' for '--%s'", v, name)
+         }
+         cfg.Mode = func(dst io.Writer, src io.Reader, _ byte, blk_size int64) error {
+            return wrap(dst, src, width, blk_size)
+         }
+         return ok
+      case "fields", "chars", "bytes":
+         list, ok := parse_list(v)
+         if ok != nil {
+            return fmt.Errorf("invalid argument '%s' for '--%s'", v, name)
+         }
+         if cfg.fields != nil || cfg.chars != nil || cfg.bytes != nil {
+            return errors.New("only one list of --fields, --chars and --bytes may be given")
+         }
+         switch name {
+            case "fields":
+               cfg.fields = list
+            case "chars":
+               cfg.chars = list
+            default:
+               cfg.bytes = list
+         }
+         return nil
+      case "delimiter":
+         if len(v) != 1 {
+            return errors.New("the delimiter must be a single character")
+         }
+         cfg.delimiter = v[0]
+         return nil
+      case "prefix":
+         cfg.prefix = v
+         return nil
+      case "suffix":
+         cfg.suffix = v
+         return nil
+      case "output-prefix":
+         cfg.output_prefix = v
+         return nil
+      case "checkpoint":
+         cfg.checkpoint = v
+         return nil
+      case "max-line-length":
+         cfg.max_line, ok = size_arg(name, v, 1, math.MaxInt64)
+         return ok
+      case "on-long-line":
+         if v != "error" && v != "break" {
+            return fmt.Errorf("invalid argument '%s' for '--%s'", v, name)
+         }
+         cfg.break_long = v
+         return nil
+      case "replay":
+         if cfg.replay, ok = time.ParseDuration(v); ok != nil || cfg.replay <= 0 {
+            return fmt.Errorf("invalid argument '%s' for '--%s'", v, name)
+         }
+         return nil
+      case "status-format":
+         if v != "text" && v != "json" {
+            return fmt.Errorf("invalid argument '%s' for '--%s'", v, name)
+         }
+         cfg.status_json = v == "json"
+         return nil
+      case "number-matching":
+         if cfg.number_match, ok = regexp.Compile(v); ok != nil {
+            return fmt.Errorf("invalid argument '%s' for '--%s'", v, name)
+         }
+         opts.Number = true
+         return nil
+      case "max-bytes":
+         opts.MaxBytes, ok = size_arg(name, v, 1, math.MaxInt64)
+         return ok
+      case "color":
+         // as with ls, a bare --color means always
+         if !given {
+            v = "always"
+         }
+         if v != "always" && v != "never" && v != "auto" {
+            return fmt.Errorf("invalid argument '%s' for '--%s'", v, name)
+         }
+         cfg.color = v
+         return nil
+      case "line-buffered":
+         if !given {
+            v = "yes"
+         }
+         if v != "yes" && v != "no" {
+            return fmt.Errorf("invalid argument '%s' for '--%s'", v, name)
+         }
+         cfg.line_buffered = v
+         return nil
+      case "line-ending":
+         if v != "lf" && v != "crlf" {
+            return fmt.Errorf("invalid argument '%s' for '--%s'", v, name)
+         }
+         opts.LineEnding = v
+         return nil
+      case "block-size":
+         cfg.block_size, ok = size_arg(name, v, 1, IO_BLK_SIZE_MAX)
+         return ok
+      case "start-offset":
+         opts.StartOffset, ok = size_arg(name, v, 0, math.MaxInt64)
+         return ok
+      case "fd":
+         fd, ok := count_arg(name, v)
+         if ok != nil {
+            return ok
+         }
+         if cfg.fds == nil {
+            cfg.fds = make(map[int]int)
+         }
+         cfg.fds[len(cfg.Files)] = fd
+         cfg.Files = append(cfg.Files, "fd " + strconv.Itoa(fd))
+         return nil
+      case "nonprinting-style":
+         if v != "caret" && v != "hex" {
+            return fmt.Errorf("invalid argument '%s' for '--%s'", v, name)
+         }
+         opts.NonprintingStyle = v
+         return nil
+      case "repeat":
+         if cfg.repeat, ok = count_arg(name, v); ok == nil && cfg.repeat == 0 {
+            ok = fmt.Errorf("invalid argument '%s' for '--%s'", v, name)
+         }
+         return ok
+      case "translate":
+         if _, ok = new_tr_table(Options{Translate: v}); ok != nil {
+            return fmt.Errorf("invalid argument '%s' for '--%s': %v", v, name, ok)
+         }
+         opts.Translate = v
+         return nil
+      case "delete":
+         if _, ok = new_tr_table(Options{Delete: v}); ok != nil {
+            return fmt.Errorf("invalid argument '%s' for '--%s': %v", v, name, ok)
+         }
+         opts.Delete = v
+         return nil
+      case "squeeze-repeats":
+         if _, ok = new_tr_table(Options{SqueezeRepeats: v}); ok != nil {
+            return fmt.Errorf("invalid argument '%s' for '--%s': %v", v, name, ok)
+         }
+         opts.SqueezeRepeats = v
+         return nil
+      case "tab-string":
+         if v == "" {
+            return fmt.Errorf("invalid argument '%s' for '--%s'", v, name)
+         }
+         opts.TabString = v
+         return nil
+      case "exclude":
+         if _, ok = filepath.Match(v, ""); ok != nil {
+            return fmt.Errorf("invalid argument '%s' for '--%s'", v, name)
+         }
+         cfg.exclude = append(cfg.exclude, v)
+         return nil
+      case "files-from":
+         cfg.files_from, cfg.files_from_at = v, len(cfg.Files)
+         return nil
+      case "lines":
+         cfg.lines_from, cfg.lines_to, ok = parse_line_range(v)
+         return ok
+      case "hexdump":
+         // the width is optional, so never taken from the next argument
+         cols := HEXDUMP_COLS_DEFAULT
+         if given {
+            if cols, ok = count_arg(name, v); ok == nil && cols == 0 {
+               ok = fmt.Errorf("invalid argument '%s' for '--%s'", v, name)
+            }
+            if ok != nil {
+               return ok
+            }
+         }
+         cfg.Filter = func(dst io.Writer) io.WriteCloser {
+            return new_hex_writer(dst, cols)
+         }
+         return nil
+   }
+   return nil
+}
+
+// parses command line args for flags, next hands out the following argument
+// to options that take one
+func checkForFlag(cfg *Config, arg string, next func() (string, bool)) (bool, error) {
+   opts := &cfg.Options
    arg_len := len(arg)
 
    // a filename
    if arg_len != 0 && arg[0] != '-' {
-      return false;
+      return false, nil;
    }
 
    if arg_len > 2 && arg[:2] == "--" {
+      name, value, attached := strings.Cut(arg[2:], "=")
+      name, ok := resolve_long(name)
+      if ok != nil {
+         return true, ok
+      }
+
+      // long flag with a value, --name=value or --name value
+      if optional, takes_value := value_options[name]; takes_value {
+         if !optional {
+            v, ok := option_arg(name, true, value, attached, next)
+            if ok != nil {
+               return true, ok
+            }
+            value, attached = v, true
+         }
+         return true, set_value_option(cfg, name, value, attached)
+      }
+
       // long flag
-      switch arg[2:] {
+      if attached && slices.Contains(flag_options, name) {
+         return true, fmt.Errorf("option '--%s' doesn't allow an argument", name)
+      }
+      switch name {
          case "number-nonblank":
-            number_nonblank = true
+            opts.NumberNonblank = true
          case "number":
-            number = true
+            opts.Number = true
          case "squeeze-blank":
-            squeeze_blank = true
+            opts.SqueezeBlank = true
+         case "squeeze-all":
+            opts.SqueezeAll = true
+         case "trim-trailing":
+            opts.TrimTrailing = true
+         case "skip-binary":
+            cfg.skip_binary = true
+         case "strip-bom":
+            cfg.strip_bom = true
+         case "collapse-whitespace":
+            cfg.collapse = true
+         case "prefix-before-number":
+            cfg.prefix_first = true
+         case "json":
+            cfg.json = true
+         case "only-delimited":
+            cfg.only_delimited = true
+         case "shuffle":
+            cfg.shuffle = true
+         case "merge":
+            cfg.merge = true
+         case "join":
+            cfg.join = true
+         case "summary":
+            cfg.summary = true
+         case "reverse":
+            cfg.reverse = true
+         case "numeric":
+            cfg.numeric = true
+         case "strip-cr":
+            opts.StripCR = true
+         case "progress":
+            cfg.progress = true
+         case "pv":
+            cfg.pv = true
+         case "resume":
+            cfg.resume = true
+         case "device-ok":
+            cfg.device_ok = true
+         case "show-io-info":
+            cfg.show_io_info = true
+         case "safe-scan":
+            cfg.Options.SafeScan = true
+         case "decompress":
+            cfg.decompress = true
+         case "ensure-final-newline":
+            cfg.ensure_newline = true
+         case "headers":
+            cfg.headers = true
+         case "verbose":
+            cfg.verbose = true
+         case "no-fionread":
+            cfg.no_fionread = true
+         case "deterministic":
+            // the same reads on any machine, FIONREAD and st_blksize aside
+            cfg.no_fionread = true
+            if cfg.block_size == 0 {
+               cfg.block_size = IO_BLK_SIZE_DEFAULT
+            }
+         case "unbuffered":
+            cfg.unbuffered = true
+         case "list-files", "check-only":
+            cfg.list_files = true
+         case "invert-match":
+            cfg.invert_match = true
+         case "number-original":
+            cfg.number_original = true
+         case "number-skipped":
+            cfg.number_skipped = true
+         case "renumber":
+            cfg.renumber = true
+         case "null":
+            cfg.null = true
+         case "recursive":
+            cfg.recursive = true
+         case "zero":
+            opts.Zero = true
+         case "uniq":
+            cfg.uniq = true
+         case "uniq-count":
+            cfg.uniq, cfg.uniq_count = true, true
+         case "dereference":
+            cfg.dereference = true
          case "show-tabs":
-            show_tabs = true
+            opts.ShowTabs = true
          case "show-ends":
-            show_ends = true
+            opts.ShowEnds = true
          case "show-all":
-            show_tabs = true
-            show_ends = true
+            opts.ShowTabs = true
+            opts.ShowEnds = true
             fallthrough
          case "show-nonprinting":
-            show_nonprinting = true
+            opts.ShowNonprinting = true
+         case "append":
+            cfg.Append = true
+         case "tac":
+            cfg.Mode = tac
+         case "rev":
+            cfg.Mode = rev_mode
+         case "count":
+            tally := &count_tally{}
+            cfg.Report, cfg.ReportTotal = tally.report, tally.total
+         case "count-only":
+            cfg.count_only = true
+         case "cksum":
+            cfg.Report = cksum_report
+         case "fold-spaces":
+            cfg.fold_spaces = true
+         case "fold-bytes":
+            cfg.fold_bytes = true
+         case "expand":
+            cfg.expand = true
+         case "unexpand":
+            cfg.unexpand = true
+         case "all-blanks":
+            cfg.all_blanks = true
+         case "base64":
+            cfg.Filter = new_base64_encoder
+         case "base64-decode":
+            cfg.Filter = new_base64_decoder
          case "version":
-            fallthrough
+            return true, ErrVersionRequested
          case "help":
-            fallthrough
+            return true, ErrHelpRequested
          default:
-            special_flag = arg[2:]
+            return true, UnknownOptionError{Name: arg[2:], Long: true}
       }
    } else if arg_len > 1 && arg[0] == '-' {
-      // shorthand flags
-      for _, c := range arg[1:] {
+      // shorthand flags, the unknown ones all reported
+      var unknown []error
+      for i, c := range arg[1:] {
+         if name, takes_value := short_value_options[c]; takes_value {
+            // the value is the rest of the cluster or the next argument
+            rest := arg[2+i:]
+            v, ok := option_arg(string(c), false, rest, rest != "", next)
+            if ok != nil {
+               return true, ok
+            }
+            unknown = append(unknown, set_value_option(cfg, name, v, true))
+            return true, errors.Join(unknown...)
+         }
+
          switch c {
          case 'b':
-            number_nonblank = true
+            opts.NumberNonblank = true
          case 'n':
-            number = true
+            opts.Number = true
          case 's':
-            squeeze_blank = true
+            opts.SqueezeBlank = true
          case 't':
-            show_tabs = true
-            show_nonprinting = true;
+            opts.ShowTabs = true
+            opts.ShowNonprinting = true;
          case 'E':
-            show_ends = true
+            opts.ShowEnds = true
          case 'A':
-            show_tabs = true
+            opts.ShowTabs = true
             fallthrough
          case 'e':
-            show_ends = true
+            opts.ShowEnds = true
             fallthrough
          case 'v':
-            show_nonprinting = true
+            opts.ShowNonprinting = true
          case 'T':
-            show_tabs = true
+            opts.ShowTabs = true
          case 'u':
             // ignored
+         case 'V':
+            cfg.verbose = true
+         case '0':
+            cfg.null = true
+         case 'R':
+            cfg.recursive = true
+         case 'z':
+            opts.Zero = true
+         case 'L':
+            cfg.dereference = true
          default:
-            special_flag = string(c)
+            unknown = append(unknown, UnknownOptionError{Name: string(c)})
          }
       }
+      return true, errors.Join(unknown...)
    } else {
-      // only "-" and "--" (STDIN re-route) get here
-      return false
+      // only "-" (STDIN re-route) gets here
+      return false, nil
    }
 
-   return true
+   return true, nil
 }
 
-func main() {
-   args := os.Args[1:]
-   n_args := len(args)
-   ret := true;
+// ParseArgs parses the command line arguments following the program name.
+// Options may appear anywhere among the files. The first --help or --version
+// is returned as the error wherever it appears; otherwise every unknown
+// option or bad value is, one errors.Join of them all.
+func ParseArgs(args []string) (Config, error) {
+   var cfg Config
+   var failed []error
+
+   for i := 0; i < len(args); i++ {
+      arg := args[i]
+      next := func() (string, bool) {
+         if i+1 < len(args) {
+            i++
+            return args[i], true
+         }
+         return "", false
+      }
+
+      // the end of the options, whatever follows is a file
+      if arg == "--" {
+         cfg.Files = append(cfg.Files, args[i+1:]...)
+         break
+      }
 
-   if n_args < 1 { // include stdin
-      args = []string{"-"}
-      n_args = n_args+1
+      is_flag, ok := checkForFlag(&cfg, arg, next)
+      if ok == ErrHelpRequested || ok == ErrVersionRequested {
+         return Config{}, ok
+      } else if ok != nil {
+         failed = append(failed, ok)
+         continue
+      }
+      if !is_flag {
+         cfg.Files = append(cfg.Files, arg)
+      }
    }
 
-   var out_stat syscall.Stat_t
-   if ok := syscall.Fstat(int(os.Stdout.Fd()), &out_stat); ok != nil {
-      panic(ok)
+   if cfg.join && len(cfg.Files) != 2 {
+      failed = append(failed, errors.New("--join takes two files"))
+   }
+   if cfg.chunk_lines > 0 && cfg.chunk_bytes > 0 {
+      failed = append(failed, errors.New("cannot split in more than one way")) // as split
+   }
+   if cfg.checkpoint != "" && (cfg.Output == "" || cfg.chunk_lines > 0 || cfg.chunk_bytes > 0) {
+      failed = append(failed, errors.New("--checkpoint needs a single --output file"))
+   }
+   if cfg.checkpoint != "" && !cfg.plain_copy() {
+      failed = append(failed, errors.New("--checkpoint only takes a plain copy, with no option that changes what is written"))
+   }
+   if cfg.summary && !cfg.Options.ShowNonprinting {
+      failed = append(failed, errors.New("--summary needs -v, which escapes what it counts"))
+   }
+   if cfg.break_long != "" && cfg.max_line == 0 {
+      failed = append(failed, errors.New("--on-long-line needs --max-line-length"))
+   }
+   if cfg.resume && cfg.checkpoint == "" {
+      failed = append(failed, errors.New("--resume needs --checkpoint"))
+   } else if cfg.resume && len(cfg.Files) > 1 {
+      failed = append(failed, errors.New("--resume takes a single file"))
+   }
+
+   if len(failed) > 0 {
+      return Config{}, errors.Join(failed...)
    }
 
-   // get stdout info for block buffers
-   out_bSize := int64(math.Max(float64(out_stat.Blksize), float64(IO_BLK_SIZE_DEFAULT)))
+   if cfg.fold_width > 0 {
+      width, spaces, count_bytes := cfg.fold_width, cfg.fold_spaces, cfg.fold_bytes
+      cfg.Mode = func(dst io.Writer, src io.Reader, _ byte, blk_size int64) error {
+         return fold(dst, src, width, spaces, count_bytes, blk_size)
+      }
+   }
 
-   // read in each file and route to stdout
-   // reverse order for defer stack
-   first_file := -1
-   for i := n_args-1; i >= 0; i-- {
-      if checkForFlag(args[i]) {
-         if i == 0 && first_file == -1 { // no FILE arg, first arg has to be a flag, route STDIN
-            args[i] = "-"
-         } else {
-            continue
-         }
+   if cfg.shuffle {
+      seed := cfg.random_seed
+      if !cfg.seeded {
+         seed = time.Now().UnixNano()
       }
+      rng := rand.New(rand.NewSource(seed))
+      cfg.Mode = func(dst io.Writer, src io.Reader, _ byte, blk_size int64) error {
+         return shuffle(dst, src, rng, blk_size)
+      }
+   }
 
-      // save "bottom of stack"
-      first_file = i;
+   tab_list, all_blanks := cfg.tab_list, cfg.all_blanks
+   if cfg.expand {
+      cfg.Mode = func(dst io.Writer, src io.Reader, _ byte, blk_size int64) error {
+         return expand(dst, src, tab_list, blk_size)
+      }
+   } else if cfg.unexpand {
+      cfg.Mode = func(dst io.Writer, src io.Reader, _ byte, blk_size int64) error {
+         return unexpand(dst, src, tab_list, all_blanks, blk_size)
+      }
+   }
 
-      // defer file processing until all flags are processed
-      // this helps prevents files from being processed at all if there is a --version or --help flag
-      defer func(x *bool, idx int) {
-         *x = *x && handle_file(args[idx], out_bSize) // process file, save successes across defers
+   if cfg.uniq {
+      counts, sep := cfg.uniq_count, byte('\n')
+      if cfg.Options.Zero {
+         sep = 0
+      }
+      cfg.Filter = func(dst io.Writer) io.WriteCloser {
+         return new_uniq_writer(dst, counts, sep)
+      }
+   }
 
-         // bottom of stack exits with success code
-         if idx == first_file && *x {
-            os.Exit(0)
-         } else if idx == first_file {
-            os.Exit(1);
-         }
-      } (&ret, i)
+   if len(cfg.Files) == 0 && cfg.files_from == "" { // include stdin
+      cfg.Files = []string{"-"}
    }
 
-   // process first special/invalid flag before handling files
-   if special_flag == "" {
-      // optimization to prevent checking all branches?
-   } else if special_flag == "help" {
+   return cfg, nil
+}
+
+func main() {
+   cfg, ok := ParseArgs(os.Args[1:])
+   if errors.Is(ok, ErrHelpRequested) {
       printUsage()
       os.Exit(0)
-   } else if special_flag == "version" {
+   } else if errors.Is(ok, ErrVersionRequested) {
       fmt.Printf("cat (Gotilities) v0.2\nAuthor: prbrown\ngithub.com/prbrown/gotilities")
       os.Exit(0)
-   } else if len(special_flag) > 1 { // invalid -- message
-      fmt.Fprintf(os.Stderr, "cat: unrecognized option '--%s'\nTry 'cat --help' for more information.\n", special_flag)
+   } else if ok != nil {
+      for _, line := range strings.Split(ok.Error(), "\n") {
+         fmt.Fprintf(os.Stderr, "cat: %s\n", line)
+      }
+      fmt.Fprintf(os.Stderr, "Try 'cat --help' for more information.\n")
       os.Exit(1)
-   } else if len(special_flag) > 0 { // invalid - message
-      fmt.Fprintf(os.Stderr, "cat: invalid option -- '%s'\nTry 'cat --help' for more information.\n", special_flag)
+   }
+
+   if cfg.files_from != "" {
+      sep := byte('\n')
+      if cfg.null {
+         sep = 0
+      }
+      names, ok := read_file_list(cfg.files_from, sep)
+      if ok != nil {
+         print_error(&cfg, ok)
+         os.Exit(1)
+      }
+      insert_files(&cfg, cfg.files_from_at, names)
+   }
+
+   out := os.Stdout
+   var chunks *chunk_writer
+   var resumed int64
+   if cfg.chunk_lines > 0 || cfg.chunk_bytes > 0 {
+      prefix := cfg.output_prefix
+      if prefix == "" {
+         prefix = cfg.Output
+      }
+      if prefix == "" {
+         prefix = "x" // as split
+      }
+      sep := byte('\n')
+      if cfg.Options.Zero {
+         sep = 0
+      }
+      chunks = new_chunk_writer(prefix, cfg.chunk_lines, cfg.chunk_bytes, sep)
+   } else if cfg.Output != "" {
+      if name, is_input := output_input(&cfg); is_input {
+         print_file_error(&cfg, name, "input file is output file")
+         os.Exit(1)
+      }
+      if cfg.resume {
+         if resumed, ok = resume_output(&cfg); ok != nil {
+            print_error(&cfg, ok)
+            os.Exit(1)
+         }
+      }
+      flags := os.O_WRONLY|os.O_CREATE|os.O_TRUNC
+      if cfg.Append {
+         flags = os.O_WRONLY|os.O_CREATE|os.O_APPEND
+      }
+      if out, ok = os.OpenFile(cfg.Output, flags, 0666); ok != nil {
+         print_error(&cfg, ok)
+         os.Exit(1)
+      }
+   }
+
+   var out_stat syscall.Stat_t
+   if ok := syscall.Fstat(int(out.Fd()), &out_stat); ok != nil {
+      panic(ok)
+   }
+
+   // get output info for block buffers
+   out_bSize := io_blksize(int64(out_stat.Blksize))
+   if cfg.block_size > 0 {
+      out_bSize = cfg.block_size
+   }
+
+   to_terminal := chunks == nil && is_terminal(out)
+   if cfg.color == "always" || cfg.color == "auto" && to_terminal {
+      cfg.Options.Color = true
+   }
+
+   var sink io.Writer = out
+   var checkpoint *checkpoint_writer
+   if chunks != nil {
+      sink = chunks
+   } else if cfg.checkpoint != "" {
+      checkpoint = new_checkpoint_writer(out, cfg.checkpoint, resumed)
+      sink = checkpoint
+   }
+   if cfg.trim_head > 0 || cfg.trim_tail > 0 {
+      sink = new_trim_writer(sink, cfg.trim_head, cfg.trim_tail)
+   }
+   if cfg.count_only {
+      sink = io.Discard // only the numbering counts
+      if !cfg.Options.NumberNonblank {
+         cfg.Options.Number = true
+      }
+   }
+   var filter io.WriteCloser
+   if cfg.Filter != nil {
+      filter = cfg.Filter(sink)
+      sink = filter
+   }
+
+   // shared across files so numbering carries over
+   st := new_cat_state(sink, cfg.Options)
+   // an empty, false or unparsed one leaves FIONREAD on
+   if env_off, _ := strconv.ParseBool(os.Getenv("GOTIL_CAT_NO_FIONREAD")); cfg.no_fionread || env_off {
+      st.use_fionread = false
+   }
+   st.line_buffered = cfg.line_buffered == "yes" || cfg.line_buffered == "" && to_terminal || cfg.replay > 0
+   st.unbuffered = cfg.unbuffered
+   if cfg.match != nil {
+      st.keep_line = match_filter(cfg.match, cfg.invert_match)
+   }
+   st.number_original = cfg.number_original
+   st.collapse = cfg.collapse
+   st.prefix, st.suffix = []byte(cfg.prefix), []byte(cfg.suffix)
+   st.prefix_first = cfg.prefix_first
+   st.json = cfg.json
+   if cfg.summary {
+      st.escape_counts = new([256]int64)
+   }
+   if cfg.fields != nil {
+      list, delim, only := cfg.fields, cfg.delimiter, cfg.only_delimited
+      if delim == 0 {
+         delim = '\t' // as cut
+      }
+      st.select_line = func(dst []byte, line []byte) ([]byte, bool) {
+         if out, ok := cut_fields(dst, line, list, delim); ok {
+            return out, true
+         }
+         return append(dst, line...), !only // no fields to pick from
+      }
+   } else if cfg.chars != nil {
+      list := cfg.chars
+      st.select_line = func(dst []byte, line []byte) ([]byte, bool) {
+         return cut_chars(dst, line, list), true
+      }
+   } else if cfg.bytes != nil {
+      list := cfg.bytes
+      st.select_line = func(dst []byte, line []byte) ([]byte, bool) {
+         return cut_bytes(dst, line, list), true
+      }
+   }
+   if cfg.number_match != nil {
+      st.number_line = cfg.number_match.Match
+      st.number_skipped = cfg.number_skipped
+   }
+   if cfg.lines_from > 0 {
+      st.lines_from, st.lines_to = cfg.lines_from, cfg.lines_to
+      st.number_original = !cfg.renumber
+   }
+
+   // on SIGINT or SIGTERM, finish up as after the last file
+   cfg.ctx = stop_on_signal()
+
+   // read in each file and route to output, a failed file doesn't stop the rest
+   ret := true
+   if cfg.recursive {
+      n_files := len(cfg.Files)
+      ret = walk_files(&cfg, cfg.dereference)
+      if len(cfg.Files) > 1 && len(cfg.Files) != n_files {
+         cfg.headers = true // which file is which
+      }
+   }
+   if cfg.check != "" {
+      ret = check_files(&cfg, st, &out_stat, out_bSize)
+      cfg.Files = nil
+   } else if cfg.list_files {
+      ret = list_files(&cfg, st)
+      cfg.Files = nil
+   } else if cfg.seq != nil || cfg.yes != nil || cfg.merge || cfg.join {
+      // (--seq, --yes, --merge, --join) output made up rather than read file
+      // by file, through the options
+      var merge_files []*os.File
+      if cfg.merge || cfg.join {
+         var opened bool
+         merge_files, opened = open_files(&cfg)
+         ret = opened && ret
+      }
+      generate := func(dst io.Writer, _ io.Reader, sep byte, blk_size int64) error {
+         if cfg.seq != nil {
+            return write_seq(dst, *cfg.seq, cfg.seq_format, sep, blk_size)
+         } else if cfg.yes != nil {
+            return write_yes(cfg.ctx, dst, *cfg.yes, sep, blk_size)
+         }
+
+         srcs := make([]io.Reader, len(merge_files))
+         for i, f := range merge_files {
+            srcs[i] = with_context(cfg.ctx, f)
+         }
+         if cfg.join {
+            if len(srcs) < 2 {
+               return nil // the other failed to open
+            }
+            return join(dst, srcs[0], srcs[1], max(cfg.join_field, 1), cfg.delimiter, cfg.join_unpaired, blk_size)
+         }
+         compare := bytes.Compare
+         if cfg.numeric {
+            compare = compare_numeric
+         }
+         if cfg.reverse {
+            forward := compare
+            compare = func(a []byte, b []byte) int { return forward(b, a) }
+         }
+         return merge(dst, srcs, compare, blk_size)
+      }
+      ok = st.run_mode(generate, bytes.NewReader(nil), out_bSize, out_bSize)
+      if ok != nil && ok != err_max_bytes && ok != context.Canceled {
+         print_error(&cfg, ok)
+         ret = false
+      }
+      for _, f := range merge_files {
+         if f != os.Stdin {
+            f.Close()
+         }
+      }
+      cfg.Files = nil
+   }
+   for i, name := range cfg.Files {
+      if cfg.headers {
+         if ok = st.write_header(name, i == 0); ok != nil {
+            if ok != err_max_bytes {
+               print_error(&cfg, ok)
+               ret = false
+            }
+            break
+         }
+      }
+      cfg.last_error = ""
+      read := st.stats.BytesRead
+      var file_ok bool
+      if fd, is_fd := cfg.fds[i]; is_fd {
+         file_ok = handle_fd(&cfg, st, fd, name, &out_stat, out_bSize)
+      } else {
+         file_ok = handle_file(&cfg, st, name, &out_stat, out_bSize)
+      }
+      ret = file_ok && ret
+      if cfg.status_json {
+         cfg.statuses = append(cfg.statuses, input_status{name, st.stats.BytesRead - read, file_ok, cfg.last_error})
+      }
+      if st.limit_reached() || st.lines_done() || cfg.ctx.Err() != nil {
+         break
+      }
+   }
+   if cfg.ReportTotal != nil && len(cfg.Files) > 1 && cfg.ctx.Err() == nil {
+      if _, ok = st.write([]byte(cfg.ReportTotal())); ok != nil && ok != err_max_bytes {
+         print_error(&cfg, ok)
+         ret = false
+      }
+   }
+
+   if filter != nil {
+      if ok = filter.Close(); ok != nil {
+         print_error(&cfg, ok)
+         ret = false
+      }
+   }
+
+   // the copy as it ends, so that a --resume of it has nothing left to do
+   if checkpoint != nil {
+      if ok = checkpoint.save(); ok != nil {
+         print_error(&cfg, ok)
+         ret = false
+      }
+   }
+
+   if cfg.summary {
+      fmt.Fprintf(os.Stderr, "cat: summary: %s\n", escape_summary(st.escape_counts))
+   }
+
+   if cfg.status_json {
+      os.Stderr.Write(append_status_json(nil, cfg.statuses))
+   }
+
+   if cfg.count_only {
+      if _, ok = fmt.Fprintln(out, st.stats.LinesNumbered); ok != nil {
+         print_error(&cfg, ok)
+         ret = false
+      }
+   }
+
+   if chunks != nil {
+      if ok = chunks.Close(); ok != nil {
+         print_error(&cfg, ok)
+         ret = false
+      }
+   }
+
+   if out != os.Stdout {
+      if ok = out.Close(); ok != nil {
+         print_error(&cfg, ok)
+         ret = false
+      }
+   }
+
+   if cfg.ctx.Err() != nil {
+      os.Exit(EXIT_INTERRUPTED)
+   }
+   if !ret {
       os.Exit(1)
    }
-}
\ No newline at end of file
+}
@@ -0,0 +1,59 @@
+// Gotilities - cat
+// Author: prbrown
+//
+// Throughput of the plain copy, -n and -v over 100 MB held in memory, as a
+// baseline for performance work.
+package main
+
+import "io"
+import "sync"
+import "bytes"
+import "testing"
+import "math/rand"
+
+const BENCH_INPUT_SIZE = 100 << 20
+
+var bench_input []byte
+var bench_input_once sync.Once
+
+// short lines of random bytes, about one newline in twelve, the same on
+// every run
+func bench_data() []byte {
+   bench_input_once.Do(func() {
+      rng := rand.New(rand.NewSource(1))
+      bench_input = make([]byte, BENCH_INPUT_SIZE)
+      rng.Read(bench_input)
+      for i := range bench_input {
+         if bench_input[i] % 12 == 0 {
+            bench_input[i] = '\n'
+         }
+      }
+   })
+   return bench_input
+}
+
+func bench_cat(b *testing.B, opts Options) {
+   data := bench_data()
+   src := bytes.NewReader(data)
+   b.SetBytes(int64(len(data)))
+   b.ResetTimer()
+   for i := 0; i < b.N; i++ {
+      src.Reset(data)
+      if _, ok := Cat(io.Discard, src, opts); ok != nil {
+         b.Fatal(ok)
+      }
+   }
+}
+
+// simple_cat, no options
+func BenchmarkSimpleCat(b *testing.B) {
+   bench_cat(b, Options{})
+}
+
+func BenchmarkNumber(b *testing.B) {
+   bench_cat(b, Options{Number: true})
+}
+
+func BenchmarkShowNonprinting(b *testing.B) {
+   bench_cat(b, Options{ShowNonprinting: true})
+}
@@ -0,0 +1,99 @@
+// Gotilities - cat
+// Author: prbrown
+//
+// FuzzCat, random input and options through Cat, NewReader, one-byte reads
+// and --safe-scan: no panics, output within its bound, and all in agreement.
+package main
+
+import "io"
+import "bytes"
+import "testing"
+
+// one_byte_reader gives out b a byte at a time, each read ending a chunk
+type one_byte_reader struct {
+   b []byte
+}
+
+func (r *one_byte_reader) Read(p []byte) (int, error) {
+   if len(r.b) == 0 {
+      return 0, io.EOF
+   }
+   if len(p) == 0 {
+      return 0, nil
+   }
+   p[0] = r.b[0]
+   r.b = r.b[1:]
+   return 1, nil
+}
+
+// the options of flags, a bit each
+func fuzz_options(flags uint16) Options {
+   opts := Options{
+      NumberNonblank: flags&1 != 0,
+      Number: flags&2 != 0,
+      SqueezeBlank: flags&4 != 0,
+      ShowNonprinting: flags&8 != 0,
+      ShowTabs: flags&16 != 0,
+      ShowEnds: flags&32 != 0,
+      TrimTrailing: flags&64 != 0,
+      SqueezeAll: flags&128 != 0,
+      Color: flags&256 != 0,
+      StripCR: flags&2048 != 0,
+   }
+   opts.NumberFormat = []string{"", "ln", "rn", "rz"}[(flags>>9)&3]
+   return opts
+}
+
+func FuzzCat(f *testing.F) {
+   f.Add([]byte("a\tb\n\n\nc\x00\xff"), uint16(0xFFFF))
+   f.Add([]byte(""), uint16(1))
+   f.Add([]byte("\n\n\n"), uint16(4|2))
+   f.Add([]byte("x  \t\n\t\n\r\n\x7f\x80"), uint16(64|8|32))
+
+   f.Fuzz(func(t *testing.T, in []byte, flags uint16) {
+      opts := fuzz_options(flags)
+
+      var out bytes.Buffer
+      if _, ok := Cat(&out, bytes.NewReader(in), opts); ok != nil {
+         t.Fatal(ok)
+      }
+
+      // each byte is at most 4 as M-^X, 14 in color, and each line adds a
+      // number; a final line without a newline counts as well
+      lines := bytes.Count(in, []byte{'\n'}) + 1
+      bound := len(in)*4 + lines*(int(LINE_COUNTER_BUF_LEN)+1)
+      if opts.Color {
+         bound = len(in)*14 + lines*(int(LINE_COUNTER_BUF_LEN)+1+len(ANSI_NUMBER)+len(ANSI_RESET))
+      }
+      if out.Len() > bound {
+         t.Fatalf("%d bytes out of %d in, over the bound of %d", out.Len(), len(in), bound)
+      }
+
+      read, ok := io.ReadAll(NewReader(bytes.NewReader(in), opts))
+      if ok != nil {
+         t.Fatal(ok)
+      }
+      if !bytes.Equal(read, out.Bytes()) {
+         t.Fatalf("NewReader gave %q, Cat %q", read, out.Bytes())
+      }
+
+      var bytewise bytes.Buffer
+      if _, ok := Cat(&bytewise, &one_byte_reader{in}, opts); ok != nil {
+         t.Fatal(ok)
+      }
+      if !bytes.Equal(bytewise.Bytes(), out.Bytes()) {
+         t.Fatalf("a byte at a time gave %q, at once %q", bytewise.Bytes(), out.Bytes())
+      }
+
+      // the line at a time reference the sentinel scan is checked against
+      var safe bytes.Buffer
+      safe_opts := opts
+      safe_opts.SafeScan = true
+      if _, ok := Cat(&safe, bytes.NewReader(in), safe_opts); ok != nil {
+         t.Fatal(ok)
+      }
+      if !bytes.Equal(safe.Bytes(), out.Bytes()) {
+         t.Fatalf("--safe-scan gave %q, the scan %q", safe.Bytes(), out.Bytes())
+      }
+   })
+}
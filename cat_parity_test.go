@@ -0,0 +1,1019 @@
+// Gotilities - cat
+// Author: prbrown
+//
+// Cat against GNU cat, byte for byte, over fixtures and every set of up to
+// three of its flags, skipped where GNU cat is not installed; and table
+// tests of the command line run whole, for the edge cases of its options.
+package main
+
+import "io"
+import "os"
+import "bytes"
+import "strings"
+import "testing"
+import "time"
+import "errors"
+import "syscall"
+import "os/exec"
+import "path/filepath"
+import "net/http"
+import "net/http/httptest"
+
+// the fixtures: tabs, NULs, every byte value, a long line, blank line runs,
+// and ends with and without a newline. CRLF is left out: since 9.1 GNU cat -E
+// shows the CR of a CRLF as ^M, which this cat does not.
+func parity_fixtures() map[string][]byte {
+   all_bytes := make([]byte, 256)
+   for i := range all_bytes {
+      all_bytes[i] = byte(i)
+   }
+   return map[string][]byte{
+      "empty": {},
+      "tabs": []byte("a\tb\t\tc\n\t\n"),
+      "nuls": []byte("\x00a\x00\n\x00\n"),
+      "bytes": all_bytes,
+      "long": append(bytes.Repeat([]byte("x\ty"), 100000), '\n'),
+      "blanks": []byte("\n\n\na\n\n\n\nb\n\n"),
+      "no_newline": []byte("one\ntwo"),
+   }
+}
+
+// the flags the harness combines, and how each reads as Options
+var parity_flags = map[string]func(*Options){
+   "-b": func(o *Options) { o.NumberNonblank = true },
+   "-e": func(o *Options) { o.ShowNonprinting, o.ShowEnds = true, true },
+   "-E": func(o *Options) { o.ShowEnds = true },
+   "-n": func(o *Options) { o.Number = true },
+   "-s": func(o *Options) { o.SqueezeBlank = true },
+   "-t": func(o *Options) { o.ShowNonprinting, o.ShowTabs = true, true },
+   "-T": func(o *Options) { o.ShowTabs = true },
+   "-u": func(o *Options) {},
+   "-v": func(o *Options) { o.ShowNonprinting = true },
+   "-A": func(o *Options) { o.ShowNonprinting, o.ShowEnds, o.ShowTabs = true, true, true },
+}
+
+// every set of up to n of flags, the empty set first
+func flag_sets(flags []string, n int) [][]string {
+   sets := [][]string{{}}
+   for i, flag := range flags {
+      if n == 0 {
+         break
+      }
+      for _, rest := range flag_sets(flags[i+1:], n-1) {
+         sets = append(sets, append([]string{flag}, rest...))
+      }
+   }
+   return sets
+}
+
+func TestParityWithGNUCat(t *testing.T) {
+   gnu_cat, ok := exec.LookPath("cat")
+   if ok != nil {
+      t.Skip("no cat to compare with")
+   }
+   if version, ok := exec.Command(gnu_cat, "--version").Output(); ok != nil || !bytes.Contains(version, []byte("GNU")) {
+      t.Skip("the cat installed is not GNU cat")
+   }
+
+   dir := t.TempDir()
+   fixtures := parity_fixtures()
+   for name, data := range fixtures {
+      if ok := os.WriteFile(filepath.Join(dir, name), data, 0666); ok != nil {
+         t.Fatal(ok)
+      }
+   }
+
+   var flags []string
+   for flag := range parity_flags {
+      flags = append(flags, flag)
+   }
+   for _, set := range flag_sets(flags, 3) {
+      var opts Options
+      for _, flag := range set {
+         parity_flags[flag](&opts)
+      }
+
+      for name, data := range fixtures {
+         want, ok := exec.Command(gnu_cat, append(set, filepath.Join(dir, name))...).Output()
+         if ok != nil {
+            t.Fatalf("cat %s %s: %v", strings.Join(set, " "), name, ok)
+         }
+         var got bytes.Buffer
+         if _, ok := Cat(&got, bytes.NewReader(data), opts); ok != nil {
+            t.Fatalf("Cat %s %s: %v", strings.Join(set, " "), name, ok)
+         }
+         if !bytes.Equal(got.Bytes(), want) {
+            t.Errorf("cat %s %s: got %q, GNU cat %q", strings.Join(set, " "), name, clip(got.Bytes()), clip(want))
+         }
+      }
+   }
+}
+
+// b cut short for a failure message
+func clip(b []byte) []byte {
+   if len(b) > 200 {
+      return b[:200]
+   }
+   return b
+}
+
+// with GOTIL_CAT_TEST_MAIN set the test binary is cat itself, so that
+// cli_case can run the command line, exit status and all
+func TestMain(m *testing.M) {
+   if os.Getenv("GOTIL_CAT_TEST_MAIN") == "1" {
+      if os.Getenv("GOTIL_CAT_TEST_TERMINAL") == "1" {
+         is_terminal = func(*os.File) bool { return true }
+      }
+      os.Args = append([]string{"cat"}, os.Args[1:]...)
+      main()
+      os.Exit(0)
+   }
+   os.Exit(m.Run())
+}
+
+// cli_case is one run of the command line in a directory of its own
+// holding files, with stdin given; stdout is matched in full, stderr as
+// a part of it, and files_after are the files as they must be left. With
+// terminal, cat takes its output and stderr for a terminal; env is added to
+// its environment.
+type cli_case struct {
+   name string
+   terminal bool
+   env []string
+   files map[string]string
+   stdin string
+   args []string
+   stdout string
+   stderr string
+   code int
+   files_after map[string]string
+}
+
+// cli_command runs this test binary as cat, on a terminal or not
+func cli_command(terminal bool, args ...string) *exec.Cmd {
+   cmd := exec.Command(os.Args[0], args...)
+   cmd.Env = append(os.Environ(), "GOTIL_CAT_TEST_MAIN=1")
+   if terminal {
+      cmd.Env = append(cmd.Env, "GOTIL_CAT_TEST_TERMINAL=1")
+   }
+   return cmd
+}
+
+func run_cli_cases(t *testing.T, cases []cli_case) {
+   for _, c := range cases {
+      t.Run(c.name, func(t *testing.T) {
+         dir := t.TempDir()
+         for name, data := range c.files {
+            if ok := os.WriteFile(filepath.Join(dir, name), []byte(data), 0666); ok != nil {
+               t.Fatal(ok)
+            }
+         }
+
+         cmd := cli_command(c.terminal, c.args...)
+         cmd.Dir = dir
+         cmd.Env = append(cmd.Env, c.env...)
+         cmd.Stdin = strings.NewReader(c.stdin)
+         var stdout, stderr bytes.Buffer
+         cmd.Stdout, cmd.Stderr = &stdout, &stderr
+         code := 0
+         if ok := cmd.Run(); ok != nil {
+            exit, is_exit := ok.(*exec.ExitError)
+            if !is_exit {
+               t.Fatal(ok)
+            }
+            code = exit.ExitCode()
+         }
+
+         if stdout.String() != c.stdout {
+            t.Errorf("cat %s: stdout %q, want %q", strings.Join(c.args, " "), clip(stdout.Bytes()), c.stdout)
+         }
+         if !strings.Contains(stderr.String(), c.stderr) || c.stderr == "" && stderr.Len() > 0 {
+            t.Errorf("cat %s: stderr %q, want %q", strings.Join(c.args, " "), stderr.String(), c.stderr)
+         }
+         if code != c.code {
+            t.Errorf("cat %s: exit status %d, want %d", strings.Join(c.args, " "), code, c.code)
+         }
+         for name, want := range c.files_after {
+            got, ok := os.ReadFile(filepath.Join(dir, name))
+            if ok != nil || string(got) != want {
+               t.Errorf("cat %s: %s holds %q (%v), want %q", strings.Join(c.args, " "), name, got, ok, want)
+            }
+         }
+      })
+   }
+}
+
+func TestTacStartOffset(t *testing.T) {
+   run_cli_cases(t, []cli_case{
+      {name: "past the end", files: map[string]string{"f": "ab\n"}, args: []string{"--start-offset=10", "--tac", "f"}},
+      {name: "at the end", files: map[string]string{"f": "ab\n"}, args: []string{"--start-offset=3", "--tac", "f"}},
+      {name: "inside", files: map[string]string{"f": "ab\ncd\nef\n"}, args: []string{"--start-offset=3", "--tac", "f"}, stdout: "ef\ncd\n"},
+   })
+}
+
+func TestStartOffset(t *testing.T) {
+   run_cli_cases(t, []cli_case{
+      {name: "inside", files: map[string]string{"f": "abcdef"}, args: []string{"--start-offset=2", "f"}, stdout: "cdef"},
+      {name: "past the end", files: map[string]string{"f": "abc"}, args: []string{"--start-offset=10", "f"}},
+      {name: "of each file", files: map[string]string{"f": "abc", "g": "defg"}, args: []string{"--start-offset=3", "f", "g"}, stdout: "g"},
+      {name: "piped", stdin: "abcdef", args: []string{"--start-offset=4"}, stdout: "ef"},
+      {name: "piped past the end", stdin: "ab", args: []string{"--start-offset=4"}},
+      {name: "repeated past the end", files: map[string]string{"f": "abc"}, args: []string{"--start-offset=5", "--repeat=2", "f"}},
+   })
+}
+
+// a seek past the end is not left there for what reads on
+func TestSkipInputClamped(t *testing.T) {
+   src := bytes.NewReader([]byte("abc"))
+   if ok := skip_input(src, 10); ok != nil {
+      t.Fatal(ok)
+   }
+   if pos, _ := src.Seek(0, io.SeekCurrent); pos != 3 {
+      t.Errorf("left at %d, want the end at 3", pos)
+   }
+}
+
+func TestCheckpointResume(t *testing.T) {
+   refused := "--checkpoint only takes a plain copy"
+   run_cli_cases(t, []cli_case{
+      {name: "resumed", files: map[string]string{"in": "0123456789", "out": "01234xx", "ck": "5\n"},
+         args: []string{"--checkpoint=ck", "--resume", "-o", "out", "in"}, files_after: map[string]string{"out": "0123456789", "ck": "10\n"}},
+      {name: "from nothing", files: map[string]string{"in": "abc"},
+         args: []string{"--checkpoint=ck", "--resume", "-o", "out", "in"}, files_after: map[string]string{"out": "abc", "ck": "3\n"}},
+      {name: "output shorter", files: map[string]string{"in": "0123456789", "out": "012", "ck": "5\n"},
+         args: []string{"--checkpoint=ck", "--resume", "-o", "out", "in"}, stderr: "shorter than the 5 bytes", code: 1, files_after: map[string]string{"out": "012"}},
+      {name: "numbered", files: map[string]string{"in": "a\nb\n", "out": "     1\ta\n", "ck": "9\n"},
+         args: []string{"--checkpoint=ck", "--resume", "-n", "-o", "out", "in"}, stderr: refused, code: 1, files_after: map[string]string{"out": "     1\ta\n"}},
+      {name: "filtered", files: map[string]string{"in": "a\nb\n"},
+         args: []string{"--checkpoint=ck", "--resume", "--match=b", "-o", "out", "in"}, stderr: refused, code: 1},
+      {name: "folded", files: map[string]string{"in": "a\nb\n"},
+         args: []string{"--checkpoint=ck", "--fold=1", "-o", "out", "in"}, stderr: refused, code: 1},
+      {name: "no output", files: map[string]string{"in": "a"},
+         args: []string{"--checkpoint=ck", "in"}, stderr: "--checkpoint needs a single --output file", code: 1},
+   })
+}
+
+func TestOutputIsInput(t *testing.T) {
+   run_cli_cases(t, []cli_case{
+      {name: "only input", files: map[string]string{"f2": "keep\n"},
+         args: []string{"-o", "f2", "f2"}, stderr: "f2: input file is output file", code: 1, files_after: map[string]string{"f2": "keep\n"}},
+      {name: "among inputs", files: map[string]string{"f1": "one\n", "f2": "keep\n"},
+         args: []string{"-o", "f2", "f1", "f2"}, stderr: "f2: input file is output file", code: 1, files_after: map[string]string{"f2": "keep\n"}},
+      {name: "other output", files: map[string]string{"f1": "one\n", "f2": "keep\n"},
+         args: []string{"-o", "f3", "f1", "f2"}, files_after: map[string]string{"f2": "keep\n", "f3": "one\nkeep\n"}},
+   })
+}
+
+func TestFilesFrom(t *testing.T) {
+   run_cli_cases(t, []cli_case{
+      {name: "dashed name", files: map[string]string{"-x": "dashed\n", "list": "-x\n"},
+         args: []string{"--files-from=list"}, stdout: "dashed\n"},
+      {name: "stdin among them", files: map[string]string{"a": "a\n", "list": "a\n-\n"}, stdin: "in\n",
+         args: []string{"--files-from=list"}, stdout: "a\nin\n"},
+   })
+}
+
+func TestEndOfOptions(t *testing.T) {
+   run_cli_cases(t, []cli_case{
+      {name: "dashed file", files: map[string]string{"-x": "dashed\n"},
+         args: []string{"--", "-x"}, stdout: "dashed\n"},
+      {name: "options before", files: map[string]string{"-n": "a\n"},
+         args: []string{"-E", "--", "-n"}, stdout: "a$\n"},
+      {name: "second terminator", files: map[string]string{"--": "dd\n", "a": "a\n"},
+         args: []string{"--", "a", "--"}, stdout: "a\ndd\n"},
+      {name: "stdin still", stdin: "in\n",
+         args: []string{"--", "-"}, stdout: "in\n"},
+      {name: "nothing after", stdin: "in\n",
+         args: []string{"--"}, stdout: "in\n"},
+      {name: "unknown without", files: map[string]string{"-x": "dashed\n"},
+         args: []string{"-x"}, stderr: "invalid option", code: 1},
+   })
+}
+
+func TestTerminalDefaults(t *testing.T) {
+   run_cli_cases(t, []cli_case{
+      {name: "color auto on a terminal", terminal: true, stdin: "a\n",
+         args: []string{"--color=auto", "-n"}, stdout: "\033[2m     1\t\033[0ma\n"},
+      {name: "color auto elsewhere", stdin: "a\n",
+         args: []string{"--color=auto", "-n"}, stdout: "     1\ta\n"},
+      {name: "color never on a terminal", terminal: true, stdin: "a\n",
+         args: []string{"--color=never", "-n"}, stdout: "     1\ta\n"},
+      {name: "line buffered on a terminal", terminal: true, stdin: "a\n",
+         args: []string{"-n", "--show-io-info"}, stdout: "     1\ta\n", stderr: "; line buffered; "},
+      {name: "block buffered elsewhere", stdin: "a\n",
+         args: []string{"-n", "--show-io-info"}, stdout: "     1\ta\n", stderr: "; block buffered; "},
+      {name: "line buffered asked for", stdin: "a\n",
+         args: []string{"-n", "--show-io-info", "--line-buffered=yes"}, stdout: "     1\ta\n", stderr: "; line buffered; "},
+      {name: "block buffered asked for", terminal: true, stdin: "a\n",
+         args: []string{"-n", "--show-io-info", "--line-buffered=no"}, stdout: "     1\ta\n", stderr: "; block buffered; "},
+   })
+}
+
+// pv_stderr is what --pv writes to stderr of an input that takes longer than
+// a report interval
+func pv_stderr(t *testing.T, terminal bool) string {
+   cmd := cli_command(terminal, "--pv")
+   in, ok := cmd.StdinPipe()
+   if ok != nil {
+      t.Fatal(ok)
+   }
+   var stderr bytes.Buffer
+   cmd.Stdout, cmd.Stderr = io.Discard, &stderr
+   if ok = cmd.Start(); ok != nil {
+      t.Fatal(ok)
+   }
+   in.Write([]byte("a\n"))
+   time.Sleep(PROGRESS_INTERVAL + 100*time.Millisecond)
+   in.Write([]byte("b\n"))
+   in.Close()
+   if ok = cmd.Wait(); ok != nil {
+      t.Fatal(ok)
+   }
+   return stderr.String()
+}
+
+func TestTerminalPV(t *testing.T) {
+   if got := pv_stderr(t, true); !strings.Contains(got, "\rcat: -: 4B") {
+      t.Errorf("--pv on a terminal wrote %q, want a bar", got)
+   }
+   if got := pv_stderr(t, false); got != "" {
+      t.Errorf("--pv off a terminal wrote %q, want nothing", got)
+   }
+}
+
+func TestBlockSize(t *testing.T) {
+   for _, c := range []struct {
+      blksize int64
+      want int64
+   }{
+      {0, IO_BLK_SIZE_DEFAULT},
+      {-1, IO_BLK_SIZE_DEFAULT},
+      {4096, IO_BLK_SIZE_DEFAULT},
+      {IO_BLK_SIZE_DEFAULT+1, IO_BLK_SIZE_DEFAULT+1},
+      {IO_BLK_SIZE_MAX, IO_BLK_SIZE_MAX},
+      {1 << 40, IO_BLK_SIZE_MAX},
+   } {
+      if got := io_blksize(c.blksize); got != c.want {
+         t.Errorf("io_blksize(%d) = %d, want %d", c.blksize, got, c.want)
+      }
+   }
+
+   run_cli_cases(t, []cli_case{
+      {name: "set", files: map[string]string{"f": "abc\n"},
+         args: []string{"--block-size=1", "--show-io-info", "f"}, stdout: "abc\n", stderr: "blocks of 1 in, 1 out"},
+      {name: "set and escaped", files: map[string]string{"f": "a\tb\n"},
+         args: []string{"--block-size=1", "-T", "f"}, stdout: "a^Ib\n"},
+      {name: "at the cap", files: map[string]string{"f": "abc\n"},
+         args: []string{"--block-size=16M", "f"}, stdout: "abc\n"},
+      {name: "over the cap", files: map[string]string{"f": "abc\n"},
+         args: []string{"--block-size=17M", "f"}, stderr: "invalid argument '17M' for '--block-size'", code: 1},
+      {name: "zero", files: map[string]string{"f": "abc\n"},
+         args: []string{"--block-size=0", "f"}, stderr: "invalid argument '0' for '--block-size'", code: 1},
+   })
+}
+
+// flaky_reader fails each read with the next of fails, if any, before it
+// hands out a byte of b; with the error a read may hand out one byte
+type flaky_reader struct {
+   b []byte
+   fails []error
+   with_data bool
+}
+
+func (r *flaky_reader) Read(p []byte) (int, error) {
+   if len(r.fails) > 0 {
+      ok := r.fails[0]
+      r.fails = r.fails[1:]
+      if r.with_data && len(r.b) > 0 {
+         p[0], r.b = r.b[0], r.b[1:]
+         return 1, ok
+      }
+      return 0, ok
+   }
+   if len(r.b) == 0 {
+      return 0, io.EOF
+   }
+   n := copy(p, r.b)
+   r.b = r.b[n:]
+   return n, nil
+}
+
+// flaky_writer takes a byte of each write that fails with the next of fails
+type flaky_writer struct {
+   bytes.Buffer
+   fails []error
+}
+
+func (w *flaky_writer) Write(p []byte) (int, error) {
+   if len(w.fails) > 0 && len(p) > 0 {
+      ok := w.fails[0]
+      w.fails = w.fails[1:]
+      w.Buffer.WriteByte(p[0])
+      return 1, ok
+   }
+   return w.Buffer.Write(p)
+}
+
+func TestRetry(t *testing.T) {
+   fatal := errors.New("fatal")
+   for _, c := range []struct {
+      name string
+      fails []error
+      with_data bool
+      want string
+      ok error
+   }{
+      {name: "EINTR twice", fails: []error{syscall.EINTR, syscall.EINTR}, want: "     1\tab\n"},
+      {name: "EAGAIN", fails: []error{syscall.EAGAIN, syscall.EAGAIN, syscall.EAGAIN}, want: "     1\tab\n"},
+      {name: "wrapped", fails: []error{&os.PathError{Op: "read", Path: "f", Err: syscall.EINTR}}, want: "     1\tab\n"},
+      {name: "with data", fails: []error{syscall.EINTR, syscall.EAGAIN}, with_data: true, want: "     1\tab\n"},
+      {name: "other errors are not", fails: []error{fatal}, ok: fatal},
+   } {
+      var out bytes.Buffer
+      _, ok := Cat(&out, &flaky_reader{b: []byte("ab\n"), fails: c.fails, with_data: c.with_data}, Options{Number: true})
+      if ok != c.ok || out.String() != c.want {
+         t.Errorf("%s: %q and %v, want %q and %v", c.name, out.String(), ok, c.want, c.ok)
+      }
+   }
+
+   w := &flaky_writer{fails: []error{syscall.EINTR, syscall.EAGAIN}}
+   if n, ok := retry_write(w, []byte("abc")); n != 3 || ok != nil || w.String() != "abc" {
+      t.Errorf("retry_write wrote %q, %d and %v, want all of \"abc\"", w.String(), n, ok)
+   }
+   w = &flaky_writer{fails: []error{syscall.EINTR, fatal}}
+   if n, ok := retry_write(w, []byte("abc")); n != 2 || ok != fatal {
+      t.Errorf("retry_write gave %d and %v, want 2 and the error", n, ok)
+   }
+}
+
+func TestURL(t *testing.T) {
+   srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+      switch r.URL.Path {
+      case "/f":
+         io.WriteString(w, "a\tb\nc\n")
+      case "/empty":
+      case "/moved":
+         http.Redirect(w, r, "/f", http.StatusFound)
+      default:
+         http.NotFound(w, r)
+      }
+   }))
+   defer srv.Close()
+   closed := httptest.NewServer(http.NotFoundHandler())
+   closed.Close()
+
+   run_cli_cases(t, []cli_case{
+      {name: "fetched", args: []string{srv.URL + "/f"}, stdout: "a\tb\nc\n"},
+      {name: "transformed", args: []string{"-nT", srv.URL + "/f"}, stdout: "     1\ta^Ib\n     2\tc\n"},
+      {name: "empty", args: []string{srv.URL + "/empty"}},
+      {name: "redirected", args: []string{srv.URL + "/moved"}, stdout: "a\tb\nc\n"},
+      {name: "not found", files: map[string]string{"g": "g\n"},
+         args: []string{srv.URL + "/none", "g"}, stdout: "g\n", stderr: "cat: " + srv.URL + "/none: 404 Not Found", code: 1},
+      {name: "among files", files: map[string]string{"g": "g\n"}, stdin: "in\n",
+         args: []string{"g", srv.URL + "/f", "-"}, stdout: "g\na\tb\nc\nin\n"},
+      {name: "numbered across", files: map[string]string{"g": "g\n"},
+         args: []string{"-n", "g", srv.URL + "/f"}, stdout: "     1\tg\n     2\ta\tb\n     3\tc\n"},
+      {name: "repeated", args: []string{"--repeat=2", srv.URL + "/f"}, stderr: "cannot repeat an input that cannot seek", code: 1},
+      {name: "refused", args: []string{closed.URL + "/f"}, stderr: "connection refused", code: 1},
+   })
+}
+
+func TestVerboseErrors(t *testing.T) {
+   run_cli_cases(t, []cli_case{
+      {name: "missing", args: []string{"-V", "nope"}, stderr: "cat: open 'nope': no such file or directory (ENOENT)\n", code: 1},
+      {name: "long form", args: []string{"--verbose", "nope"}, stderr: "cat: open 'nope': no such file or directory (ENOENT)\n", code: 1},
+      {name: "not a directory", files: map[string]string{"f": "x"},
+         args: []string{"-V", "f/x"}, stderr: "cat: open 'f/x': not a directory (ENOTDIR)\n", code: 1},
+      {name: "going on", files: map[string]string{"f": "x"},
+         args: []string{"-V", "nope", "f"}, stdout: "x", stderr: "(ENOENT)", code: 1},
+      {name: "without", args: []string{"nope"}, stderr: "open nope: no such file or directory\n", code: 1},
+   })
+
+   if os.Geteuid() == 0 {
+      t.Skip("root opens any file, so there is no EACCES to see")
+   }
+   dir := t.TempDir()
+   locked := filepath.Join(dir, "locked")
+   if ok := os.WriteFile(locked, []byte("x"), 0); ok != nil {
+      t.Fatal(ok)
+   }
+   var stderr bytes.Buffer
+   cmd := cli_command(false, "-V", locked)
+   cmd.Stderr = &stderr
+   cmd.Run()
+   if want := "cat: open '" + locked + "': permission denied (EACCES)\n"; stderr.String() != want {
+      t.Errorf("stderr %q, want %q", stderr.String(), want)
+   }
+}
+
+func TestWriter(t *testing.T) {
+   long := strings.Repeat("x", int(IO_BLK_SIZE_DEFAULT)+10) + "\n"
+   inputs := map[string]string{
+      "empty": "",
+      "lines": "a\tb\nc\n",
+      "no final newline": "a\nb",
+      "blanks": "a\n\n\n\nb\n",
+      "crlf": "a\r\nb\r\n\r\n",
+      "lone cr": "a\rb\r",
+      "binary": "\x00\x7f\x80\xff\n",
+      "long line": long,
+   }
+   option_sets := map[string]Options{
+      "none": {},
+      "-n": {Number: true},
+      "-b": {NumberNonblank: true},
+      "-s": {SqueezeBlank: true},
+      "-A": {ShowNonprinting: true, ShowEnds: true, ShowTabs: true},
+      "--strip-cr": {StripCR: true},
+      "-ns --start-offset": {Number: true, SqueezeBlank: true, StartOffset: 2},
+   }
+   for in_name, in := range inputs {
+      for opts_name, opts := range option_sets {
+         var batch bytes.Buffer
+         if _, ok := Cat(&batch, strings.NewReader(in), opts); ok != nil {
+            t.Fatal(ok)
+         }
+
+         // whole, in two, and a byte or three at a time; the long line in
+         // blocks, a byte at a time being slow
+         cuts := []int{len(in)+1, len(in)/2+1, 1, 3}
+         if len(in) > 100 {
+            cuts = []int{len(in)+1, len(in)/2+1, 4093}
+         }
+         for _, cut := range cuts {
+            var pushed bytes.Buffer
+            w := NewWriter(&pushed, opts)
+            for i := 0; i < len(in); i += cut {
+               if n, ok := w.Write([]byte(in[i:min(i+cut, len(in))])); ok != nil || n != min(cut, len(in)-i) {
+                  t.Fatalf("%s, %s: Write gave %d and %v", in_name, opts_name, n, ok)
+               }
+            }
+            if ok := w.Close(); ok != nil {
+               t.Fatal(ok)
+            }
+            if !bytes.Equal(pushed.Bytes(), batch.Bytes()) {
+               t.Errorf("%s, %s, writes of %d: %q, the batch %q", in_name, opts_name, cut, clip(pushed.Bytes()), clip(batch.Bytes()))
+            }
+         }
+      }
+   }
+
+   var out bytes.Buffer
+   w := NewWriter(&out, Options{Number: true})
+   w.Write([]byte("a\nb"))
+   if out.String() != "     1\ta\n" {
+      t.Errorf("before Flush, %q, want the complete line only", out.String())
+   }
+   if w.Flush(); out.String() != "     1\ta\n     2\tb" {
+      t.Errorf("after Flush, %q, want the partial line too", out.String())
+   }
+   if w.Close(); w.Close() != nil {
+      t.Error("a second Close failed")
+   }
+   if _, ok := w.Write([]byte("c")); ok != err_writer_closed {
+      t.Errorf("a Write after Close gave %v, want %v", ok, err_writer_closed)
+   }
+}
+
+// signalled runs cat on a pipe, writes in to it, and once out has come
+// through sends each of sigs; what else cat writes, and how it exits
+func signalled(t *testing.T, in string, out string, sigs []os.Signal, args ...string) (string, int, time.Duration) {
+   cmd := cli_command(false, args...)
+   stdin, ok := cmd.StdinPipe()
+   if ok != nil {
+      t.Fatal(ok)
+   }
+   defer stdin.Close()
+   stdout, ok := cmd.StdoutPipe()
+   if ok != nil {
+      t.Fatal(ok)
+   }
+   if ok = cmd.Start(); ok != nil {
+      t.Fatal(ok)
+   }
+   io.WriteString(stdin, in)
+   got := make([]byte, len(out))
+   if _, ok = io.ReadFull(stdout, got); ok != nil || string(got) != out {
+      t.Fatalf("cat %s: %q before the signal, want %q", strings.Join(args, " "), got, out)
+   }
+
+   start := time.Now()
+   for i, sig := range sigs {
+      if i > 0 {
+         time.Sleep(50*time.Millisecond) // one signal pending merges with another
+      }
+      cmd.Process.Signal(sig)
+   }
+   rest, _ := io.ReadAll(stdout)
+   cmd.Wait()
+   return string(rest), cmd.ProcessState.ExitCode(), time.Since(start)
+}
+
+func TestSignals(t *testing.T) {
+   for _, c := range []struct {
+      name string
+      sigs []os.Signal
+      args []string
+      slow bool
+   }{
+      {name: "SIGINT", sigs: []os.Signal{os.Interrupt}, args: []string{"-n"}, slow: true},
+      {name: "SIGTERM", sigs: []os.Signal{syscall.SIGTERM}, args: []string{"-n"}, slow: true},
+      {name: "again", sigs: []os.Signal{os.Interrupt, os.Interrupt}, args: []string{"-n"}},
+      {name: "plain copy", sigs: []os.Signal{syscall.SIGTERM}, slow: true},
+   } {
+      in, out := "a\nb", "a\nb"
+      if len(c.args) > 0 {
+         out = "     1\ta\n     2\tb"
+      }
+      // cat is blocked reading the pipe, so goes only once the grace is up,
+      // unless signalled twice
+      rest, code, took := signalled(t, in, out, c.sigs, c.args...)
+      if rest != "" || code != EXIT_INTERRUPTED {
+         t.Errorf("%s: %q after the signal and exit status %d, want nothing and %d", c.name, rest, code, EXIT_INTERRUPTED)
+      }
+      if c.slow && took < SIGNAL_GRACE/2 || !c.slow && took > SIGNAL_GRACE/2 {
+         t.Errorf("%s: exited %v after the signal, against a grace of %v", c.name, took, SIGNAL_GRACE)
+      }
+   }
+
+   // not blocked, --yes stops at once, what it made written out whole
+   rest, code, took := signalled(t, "", "y\n", []os.Signal{os.Interrupt}, "--yes")
+   if strings.Trim(rest, "y\n") != "" || !strings.HasSuffix("y\n" + rest, "y\n") || code != EXIT_INTERRUPTED || took > SIGNAL_GRACE/2 {
+      t.Errorf("--yes: %q after the signal, exit status %d, %v on, want whole lines and %d at once", clip([]byte(rest)), code, took, EXIT_INTERRUPTED)
+   }
+}
+
+// what cat writes for --help, to match against
+func help_text(t *testing.T) string {
+   out, ok := cli_command(false, "--help").Output()
+   if ok != nil {
+      t.Fatal(ok)
+   }
+   return string(out)
+}
+
+func TestUnknownOptions(t *testing.T) {
+   help := help_text(t)
+   version := "cat (Gotilities) v0.2\nAuthor: prbrown\ngithub.com/prbrown/gotilities"
+   run_cli_cases(t, []cli_case{
+      {name: "help after junk", args: []string{"--bogus", "--help"}, stdout: help},
+      {name: "help before junk", args: []string{"--help", "--bogus"}, stdout: help},
+      {name: "help among files and junk", args: []string{"nope", "-q", "--help", "--bogus=1"}, stdout: help},
+      {name: "version after junk", args: []string{"--bogus", "--version"}, stdout: version},
+      {name: "first of help and version", args: []string{"--version", "--help"}, stdout: version},
+      {name: "every unknown", args: []string{"--bogus", "-n", "--junk", "-q"},
+         stderr: "cat: unrecognized option '--bogus'\ncat: unrecognized option '--junk'\ncat: invalid option -- 'q'\nTry 'cat --help' for more information.\n", code: 1},
+      {name: "with a value", args: []string{"--bogus=1"}, stderr: "cat: unrecognized option '--bogus=1'\n", code: 1},
+      {name: "in a cluster", args: []string{"-nqz"}, stderr: "cat: invalid option -- 'q'\n", code: 1},
+      {name: "ambiguous both", args: []string{"--a", "--b"},
+         stderr: "cat: option '--a' is ambiguous; possibilities: '--all-blanks' '--append' '--ascii-only'\ncat: option '--b' is ambiguous;", code: 1},
+      {name: "unknown and bad value", args: []string{"--bogus", "--block-size=0"},
+         stderr: "cat: unrecognized option '--bogus'\ncat: invalid argument '0' for '--block-size'\n", code: 1},
+      {name: "valid ones kept", files: map[string]string{"f": "a\n"}, args: []string{"-n", "f"}, stdout: "     1\ta\n"},
+   })
+}
+
+func TestAbbreviations(t *testing.T) {
+   in := map[string]string{"f": "a\n\n\nb\tc\n"}
+   run_cli_cases(t, []cli_case{
+      {name: "exact though a prefix", files: in, args: []string{"--number", "f"}, stdout: "     1\ta\n     2\t\n     3\t\n     4\tb\tc\n"},
+      {name: "unique", files: in, args: []string{"--number-n", "f"}, stdout: "     1\ta\n\n\n     2\tb\tc\n"},
+      {name: "unique squeeze", files: in, args: []string{"--squeeze-b", "f"}, stdout: "a\n\nb\tc\n"},
+      {name: "unique show", files: in, args: []string{"--show-t", "f"}, stdout: "a\n\n\nb^Ic\n"},
+      {name: "with a value", files: in, args: []string{"--block-s=1", "--show-e", "f"}, stdout: "a$\n$\n$\nb\tc$\n"},
+      {name: "value given apart", files: in, args: []string{"--block-s", "1", "f"}, stdout: "a\n\n\nb\tc\n"},
+      {name: "version", args: []string{"--vers"}, stdout: "cat (Gotilities) v0.2\nAuthor: prbrown\ngithub.com/prbrown/gotilities"},
+      {name: "num", args: []string{"--num"}, stderr: "cat: option '--num' is ambiguous; possibilities: '--number' '--number-format'", code: 1},
+      {name: "show", args: []string{"--show"},
+         stderr: "cat: option '--show' is ambiguous; possibilities: '--show-all' '--show-ends' '--show-io-info' '--show-nonprinting' '--show-tabs'\n", code: 1},
+      {name: "squeeze", args: []string{"--squeeze"},
+         stderr: "cat: option '--squeeze' is ambiguous; possibilities: '--squeeze-all' '--squeeze-blank' '--squeeze-repeats'\n", code: 1},
+      {name: "ambiguous with a value", args: []string{"--squeeze=x"}, stderr: "cat: option '--squeeze' is ambiguous;", code: 1},
+      {name: "a flag's value refused", args: []string{"--squeeze-b=x"}, stderr: "cat: option '--squeeze-blank' doesn't allow an argument\n", code: 1},
+   })
+}
+
+// failing_writer takes room bytes, then fails; short, it takes fewer than
+// it is given without an error
+type failing_writer struct {
+   room int
+   short bool
+   got bytes.Buffer
+}
+
+var err_full = errors.New("full")
+
+func (w *failing_writer) Write(p []byte) (int, error) {
+   n := min(w.room, len(p))
+   w.room -= n
+   w.got.Write(p[:n])
+   if n < len(p) && !w.short {
+      return n, err_full
+   }
+   return n, nil
+}
+
+func TestWriteErrors(t *testing.T) {
+   for _, c := range []struct {
+      name string
+      in string
+      opts Options
+      room int
+      short bool
+      ok error
+   }{
+      {name: "plain", in: "a\nb\n", room: 1, ok: err_full},
+      {name: "transformed", in: "a\nb\n", opts: Options{Number: true}, room: 3, ok: err_full},
+      {name: "nothing taken", in: "a\n", opts: Options{ShowEnds: true}, ok: err_full},
+      {name: "at the end", in: "a\nb", opts: Options{Number: true}, room: len("     1\ta\n"), ok: err_full},
+      {name: "short", in: "a\nb\n", opts: Options{Number: true}, room: 2, short: true, ok: io.ErrShortWrite},
+      {name: "room enough", in: "a\n", opts: Options{Number: true}, room: 100},
+   } {
+      // one byte at a time, so that the write before each read is the one
+      // to fail
+      for _, src := range []io.Reader{strings.NewReader(c.in), &one_byte_reader{[]byte(c.in)}} {
+         w := &failing_writer{room: c.room, short: c.short}
+         if _, ok := Cat(w, src, c.opts); !errors.Is(ok, c.ok) {
+            t.Errorf("%s: %v, want %v", c.name, ok, c.ok)
+         }
+      }
+   }
+
+   run_cli_cases(t, []cli_case{
+      {name: "full", files: map[string]string{"f": "a\n"}, args: []string{"-n", "-o", "/dev/full", "f"}, stderr: "write /dev/full: no space left on device", code: 1},
+      {name: "full plain", files: map[string]string{"f": "a\n"}, args: []string{"-o", "/dev/full", "f"}, stderr: "write /dev/full: no space left on device", code: 1},
+   })
+}
+
+// filling_reader fills every read whole from b until it runs out, keeping
+// where each read's buffer starts and how long it is
+type filling_reader struct {
+   b []byte
+   starts []*byte
+   lens []int
+}
+
+func (r *filling_reader) Read(p []byte) (int, error) {
+   if len(p) > 0 {
+      r.starts = append(r.starts, &p[0])
+      r.lens = append(r.lens, len(p))
+   }
+   if len(r.b) == 0 {
+      return 0, io.EOF
+   }
+   n := copy(p, r.b)
+   r.b = r.b[n:]
+   return n, nil
+}
+
+// the sentinel goes in the slot kept for it, so that every read is into the
+// one buffer however exactly the reads fill it
+func TestSentinelInPlace(t *testing.T) {
+   const in_size = 8
+   for _, in := range []string{
+      "abcdefgh", // one read, exactly full
+      "abcdefg\nabcdefgh", // full, ending on a newline, then full again
+      "a\tb\nc\nd\ne\n\n\n\nf\nghijklmno\n", // lines across the reads
+      "\n\n\n\n\n\n\n\n\n\n\n\n\n\n\n\n",
+   } {
+      for _, opts := range []Options{{Number: true}, {ShowNonprinting: true, ShowEnds: true, ShowTabs: true}, {SqueezeBlank: true, ShowEnds: true}} {
+         var want bytes.Buffer
+         if _, ok := Cat(&want, strings.NewReader(in), opts); ok != nil {
+            t.Fatal(ok)
+         }
+
+         var out bytes.Buffer
+         src := &filling_reader{b: []byte(in)}
+         st := new_cat_state(&out, opts)
+         if ok := st.run(src, in_size, in_size); ok != nil {
+            t.Fatal(ok)
+         }
+         if !bytes.Equal(out.Bytes(), want.Bytes()) {
+            t.Errorf("%q: %q in blocks of %d, want %q", in, out.Bytes(), in_size, want.Bytes())
+         }
+         for i := range src.starts {
+            if src.starts[i] != src.starts[0] || src.lens[i] != in_size {
+               t.Errorf("%q: read %d not into the one buffer of %d", in, i, in_size)
+               break
+            }
+         }
+      }
+   }
+}
+
+func TestDevices(t *testing.T) {
+   refused := "cat: /dev/zero: is a device; use --max-bytes\n"
+   run_cli_cases(t, []cli_case{
+      {name: "unlimited", args: []string{"/dev/zero"}, stderr: refused, code: 1},
+      {name: "limited", args: []string{"--max-bytes=4", "/dev/zero"}, stdout: "\x00\x00\x00\x00"},
+      {name: "limited and escaped", args: []string{"-v", "--max-bytes=3", "/dev/zero"}, stdout: "^@^"},
+      {name: "allowed", args: []string{"--device-ok", "--head-bytes=3", "/dev/zero"}, stdout: "\x00\x00\x00"},
+      {name: "by head alone", args: []string{"--head-bytes=3", "/dev/zero"}, stderr: refused, code: 1},
+      {name: "others go on", files: map[string]string{"f": "a\n"}, args: []string{"/dev/zero", "f"}, stdout: "a\n", stderr: refused, code: 1},
+      {name: "null", args: []string{"/dev/null"}},
+   })
+
+   // as stdin a device is read, the shell having asked for it
+   zero, ok := os.Open("/dev/zero")
+   if ok != nil {
+      t.Fatal(ok)
+   }
+   defer zero.Close()
+   cmd := cli_command(false)
+   cmd.Stdin = zero
+   stdout, ok := cmd.StdoutPipe()
+   if ok != nil {
+      t.Fatal(ok)
+   }
+   if ok = cmd.Start(); ok != nil {
+      t.Fatal(ok)
+   }
+   got := make([]byte, 3)
+   _, ok = io.ReadFull(stdout, got)
+   cmd.Process.Kill()
+   cmd.Wait()
+   if ok != nil || !bytes.Equal(got, []byte{0, 0, 0}) {
+      t.Errorf("/dev/zero as stdin gave %q and %v, want its zeros", got, ok)
+   }
+}
+
+func TestShowIOInfo(t *testing.T) {
+   in := map[string]string{"f": "a\tb\n", "g": "c\n"}
+   run_cli_cases(t, []cli_case{
+      {name: "plain", files: in, args: []string{"--show-io-info", "f"}, stdout: "a\tb\n",
+         stderr: " in, 131072 out; a buffer of 131072; block buffered; FIONREAD on\n"},
+      {name: "transformed", files: in, args: []string{"--show-io-info", "-n", "f"}, stdout: "     1\ta\tb\n",
+         stderr: "; buffers of 131073 in, 655378 out; block buffered; FIONREAD on\n"},
+      {name: "stdin", stdin: "x\n", args: []string{"--show-io-info"}, stdout: "x\n", stderr: "cat: -: st_blksize "},
+      {name: "sized", files: in, args: []string{"--show-io-info", "--block-size=4K", "f"}, stdout: "a\tb\n",
+         stderr: "; blocks of 4096 in, 4096 out; a buffer of 4096;"},
+      {name: "without FIONREAD", files: in, args: []string{"--show-io-info", "--no-fionread", "--unbuffered", "-n", "f"}, stdout: "     1\ta\tb\n", stderr: "; unbuffered; FIONREAD off\n"},
+      {name: "a failed file", args: []string{"--show-io-info", "nope"}, stderr: "cat:  open nope: no such file or directory\n", code: 1},
+   })
+
+   // a line for each file, apart from the output
+   cmd := cli_command(false, "--show-io-info", "f", "-", "g")
+   cmd.Dir = t.TempDir()
+   for name, data := range in {
+      if ok := os.WriteFile(filepath.Join(cmd.Dir, name), []byte(data), 0666); ok != nil {
+         t.Fatal(ok)
+      }
+   }
+   cmd.Stdin = strings.NewReader("x\n")
+   var stdout, stderr bytes.Buffer
+   cmd.Stdout, cmd.Stderr = &stdout, &stderr
+   if ok := cmd.Run(); ok != nil {
+      t.Fatal(ok)
+   }
+   if stdout.String() != "a\tb\nx\nc\n" {
+      t.Errorf("stdout %q, want the files alone", stdout.String())
+   }
+   lines := strings.Split(strings.TrimSuffix(stderr.String(), "\n"), "\n")
+   if len(lines) != 3 || !strings.HasPrefix(lines[0], "cat: f: ") || !strings.HasPrefix(lines[1], "cat: -: ") || !strings.HasPrefix(lines[2], "cat: g: ") {
+      t.Errorf("stderr %q, want a line for each of f, - and g", stderr.String())
+   }
+}
+
+// eager_reader hands out chunks, the last with fail alongside it, and fails
+// the test if read again after that
+type eager_reader struct {
+   t *testing.T
+   chunks []string
+   fail error
+   done bool
+}
+
+func (r *eager_reader) Read(p []byte) (int, error) {
+   if r.done {
+      r.t.Error("read again after the end")
+      return 0, r.fail
+   }
+   n := copy(p, r.chunks[0])
+   if n < len(r.chunks[0]) {
+      r.chunks[0] = r.chunks[0][n:]
+      return n, nil
+   }
+   r.chunks = r.chunks[1:]
+   if len(r.chunks) == 0 {
+      r.done = true
+      return n, r.fail
+   }
+   return n, nil
+}
+
+func TestDataWithEOF(t *testing.T) {
+   for _, c := range []struct {
+      name string
+      chunks []string
+      fail error
+      opts Options
+      want string
+   }{
+      {name: "last chunk", chunks: []string{"a\n", "b\nc"}, fail: io.EOF, opts: Options{Number: true}, want: "     1\ta\n     2\tb\n     3\tc"},
+      {name: "only chunk", chunks: []string{"a\tb\n"}, fail: io.EOF, opts: Options{ShowTabs: true, ShowEnds: true}, want: "a^Ib$\n"},
+      {name: "plain", chunks: []string{"a\n", "b"}, fail: io.EOF, want: "a\nb"},
+      {name: "empty with it", chunks: []string{"a\n", ""}, fail: io.EOF, opts: Options{Number: true}, want: "     1\ta\n"},
+      {name: "squeezed across", chunks: []string{"a\n\n", "\n\nb\n"}, fail: io.EOF, opts: Options{SqueezeBlank: true}, want: "a\n\nb\n"},
+      {name: "error", chunks: []string{"a\n", "b\n"}, fail: err_full, opts: Options{Number: true}, want: "     1\ta\n     2\tb\n"},
+      {name: "error plain", chunks: []string{"a\n", "b\n"}, fail: err_full, want: "a\nb\n"},
+   } {
+      want_ok := c.fail
+      if want_ok == io.EOF {
+         want_ok = nil
+      }
+      var out bytes.Buffer
+      _, ok := Cat(&out, &eager_reader{t: t, chunks: append([]string{}, c.chunks...), fail: c.fail}, c.opts)
+      if ok != want_ok || out.String() != c.want {
+         t.Errorf("%s: Cat gave %q and %v, want %q and %v", c.name, out.String(), ok, c.want, want_ok)
+      }
+
+      read, ok := io.ReadAll(NewReader(&eager_reader{t: t, chunks: append([]string{}, c.chunks...), fail: c.fail}, c.opts))
+      if ok != want_ok || string(read) != c.want {
+         t.Errorf("%s: NewReader gave %q and %v, want %q and %v", c.name, read, ok, c.want, want_ok)
+      }
+   }
+}
+
+func TestNoFionreadEnv(t *testing.T) {
+   var cases []cli_case
+   for _, c := range []struct {
+      value string
+      state string
+   }{
+      {"1", "off"}, {"true", "off"}, {"T", "off"},
+      {"0", "on"}, {"false", "on"}, {"", "on"}, {"yes", "on"},
+   } {
+      cases = append(cases, cli_case{name: "GOTIL_CAT_NO_FIONREAD=" + c.value, files: map[string]string{"f": "a\n"}, env: []string{"GOTIL_CAT_NO_FIONREAD=" + c.value},
+         args: []string{"--show-io-info", "f"}, stdout: "a\n", stderr: "; FIONREAD " + c.state + "\n"})
+   }
+   cases = append(cases, cli_case{name: "the option over the variable", files: map[string]string{"f": "a\n"}, env: []string{"GOTIL_CAT_NO_FIONREAD=0"},
+      args: []string{"--show-io-info", "--no-fionread", "f"}, stdout: "a\n", stderr: "; FIONREAD off\n"})
+   run_cli_cases(t, cases)
+}
+
+func TestCksum(t *testing.T) {
+   in := map[string]string{"f": "abc\n", "-x": "abc"}
+   run_cli_cases(t, []cli_case{
+      {name: "no operands", stdin: "abc", args: []string{"--cksum"}, stdout: "1219131554 3\n"},
+      {name: "stdin named", stdin: "abc", args: []string{"--cksum", "-"}, stdout: "1219131554 3\n"},
+      {name: "empty", args: []string{"--cksum"}, stdout: "4294967295 0\n"},
+      {name: "a file", files: in, args: []string{"--cksum", "f"}, stdout: "1112837078 4 f\n"},
+      {name: "files and stdin", files: in, stdin: "abc", args: []string{"--cksum", "f", "-", "--", "-x"}, stdout: "1112837078 4 f\n1219131554 3\n1219131554 3 -x\n"},
+   })
+}
+
+func TestCountTotal(t *testing.T) {
+   in := map[string]string{"f": "a\nb\n", "g": "x y\n"}
+   run_cli_cases(t, []cli_case{
+      {name: "one file", files: in, args: []string{"--count", "f"}, stdout: "      2       2       4 f\n"},
+      {name: "stdin", stdin: "a b c", args: []string{"--count"}, stdout: "      0       3       5 -\n"},
+      {name: "two files", files: in, args: []string{"--count", "f", "g"},
+         stdout: "      2       2       4 f\n      1       2       4 g\n      3       4       8 total\n"},
+      {name: "with stdin", files: in, stdin: "z\n", args: []string{"--count", "f", "-"},
+         stdout: "      2       2       4 f\n      1       1       2 -\n      3       3       6 total\n"},
+      {name: "one failed", files: in, args: []string{"--count", "f", "nope"},
+         stdout: "      2       2       4 f\n      2       2       4 total\n", stderr: "open nope: no such file or directory", code: 1},
+      {name: "cksum has none", files: in, args: []string{"--cksum", "f", "g"}, stdout: "2174511615 4 f\n3029706907 4 g\n"},
+   })
+}
+
+func TestProgress(t *testing.T) {
+   in := map[string]string{"f": "abcd", "g": "", "h": "xy\n"}
+   run_cli_cases(t, []cli_case{
+      {name: "a quick file", files: in, args: []string{"--progress", "f"}, stdout: "abcd", stderr: "cat: f: 4 bytes (100%)\n"},
+      {name: "each file", files: in, args: []string{"--progress", "f", "h"}, stdout: "abcdxy\n", stderr: "cat: f: 4 bytes (100%)\ncat: h: 3 bytes (100%)\n"},
+      {name: "empty", files: in, args: []string{"--progress", "g"}, stderr: "cat: g: 0 bytes\n"},
+      {name: "stdin", stdin: "abc", args: []string{"--progress"}, stdout: "abc", stderr: "cat: -: 3 bytes\n"},
+      {name: "pv off a terminal", files: in, args: []string{"--pv", "f"}, stdout: "abcd"},
+      {name: "pv on a terminal", terminal: true, files: in, args: []string{"--pv", "f"}, stdout: "abcd", stderr: "\rcat: f: 4B "},
+   })
+}
+
+func TestHeaders(t *testing.T) {
+   in := map[string]string{"f": "a\n\n\n", "g": "\n\nb\n", "h": "c"}
+   run_cli_cases(t, []cli_case{
+      {name: "three files", files: in, args: []string{"--headers", "f", "g", "h"},
+         stdout: "==> f <==\na\n\n\n\n==> g <==\n\n\nb\n\n==> h <==\nc"},
+      {name: "stdin", files: in, stdin: "in\n", args: []string{"--headers", "h", "-"},
+         stdout: "==> h <==\nc\n==> standard input <==\nin\n"},
+      {name: "numbering carries on", files: in, args: []string{"--headers", "-n", "f", "h"},
+         stdout: "==> f <==\n     1\ta\n     2\t\n     3\t\n\n==> h <==\n     4\tc"},
+      {name: "squeezed within each file", files: in, args: []string{"--headers", "-s", "f", "g"},
+         stdout: "==> f <==\na\n\n\n==> g <==\n\nb\n"},
+   })
+}
+
+func TestZeroModes(t *testing.T) {
+   in := map[string]string{"f": "a\x00b\nc\x00d\x00"}
+   run_cli_cases(t, []cli_case{
+      {name: "tac", files: in, args: []string{"-z", "--tac", "f"}, stdout: "d\x00b\nc\x00a\x00"},
+      {name: "tac piped", files: in, stdin: "a\x00b\x00", args: []string{"--tac", "-z"}, stdout: "b\x00a\x00"},
+      {name: "rev", files: in, args: []string{"-z", "--rev", "f"}, stdout: "a\x00c\nb\x00d\x00"},
+      {name: "head", files: in, args: []string{"-z", "--head=2", "f"}, stdout: "a\x00b\nc\x00"},
+      {name: "tail", files: in, args: []string{"-z", "--tail=2", "f"}, stdout: "b\nc\x00d\x00"},
+      {name: "tail piped", stdin: "a\x00b\nc\x00d\x00", args: []string{"-z", "--tail=1"}, stdout: "d\x00"},
+      {name: "option order", files: in, args: []string{"--head=1", "-z", "f"}, stdout: "a\x00"},
+      {name: "newlines without", files: in, args: []string{"--tac", "f"}, stdout: "c\x00d\x00a\x00b\n"},
+      {name: "numbered", files: in, args: []string{"-z", "-n", "--head=2", "f"}, stdout: "     1\ta\x00     2\tb\nc\x00"},
+   })
+}
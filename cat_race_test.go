@@ -0,0 +1,55 @@
+// Gotilities - cat
+// Author: prbrown
+//
+// Concurrent Cat calls sharing one Options, each numbering and counting on
+// its own; run under go test -race.
+package main
+
+import "fmt"
+import "sync"
+import "bytes"
+import "strings"
+import "testing"
+
+const RACE_CALLS = 50
+
+func TestConcurrentCat(t *testing.T) {
+   opts := Options{Number: true, SqueezeBlank: true, ShowEnds: true}
+
+   var wg sync.WaitGroup
+   failures := make(chan string, 2*RACE_CALLS) // room for each call to fail both ways
+   for call := 0; call < RACE_CALLS; call++ {
+      wg.Add(1)
+      go func(call int) {
+         defer wg.Done()
+
+         // a different count of lines for each call, so shared numbering
+         // would show
+         var in, want strings.Builder
+         n_lines := 100 + call
+         for i := 1; i <= n_lines; i++ {
+            fmt.Fprintf(&in, "call %d line %d\n\n\n", call, i)
+            fmt.Fprintf(&want, "%6d\tcall %d line %d$\n%6d\t$\n", 2*i-1, call, i, 2*i)
+         }
+
+         var out bytes.Buffer
+         stats, ok := Cat(&out, strings.NewReader(in.String()), opts)
+         if ok != nil {
+            failures <- fmt.Sprintf("call %d: %v", call, ok)
+            return
+         }
+         if out.String() != want.String() {
+            failures <- fmt.Sprintf("call %d: numbering or output not its own", call)
+         }
+         if stats.LinesNumbered != int64(2*n_lines) || stats.BlankLinesSqueezed != int64(n_lines) || stats.BytesRead != int64(in.Len()) {
+            failures <- fmt.Sprintf("call %d: stats %+v not its own", call, stats)
+         }
+      }(call)
+   }
+   wg.Wait()
+   close(failures)
+
+   for failure := range failures {
+      t.Error(failure)
+   }
+}
@@ -0,0 +1,120 @@
+// Gotilities - sha256sum -c, sha1sum -c, md5sum -c
+// Author: prbrown
+//
+// --check, files verified against a list of their digests as the coreutils
+// *sum tools print them.
+package main
+
+import "os"
+import "io"
+import "fmt"
+import "bufio"
+import "bytes"
+import "strings"
+import "syscall"
+import "encoding/hex"
+
+// a line of a --check list
+type check_entry struct {
+   sum []byte
+   name string
+}
+
+// the --digest algorithm whose sums are size bytes long
+func digest_for_size(size int) (string, bool) {
+   for _, name := range []string{"md5", "sha1", "sha256"} {
+      if digest_algorithms[name]().Size() == size {
+         return name, true
+      }
+   }
+   return "", false
+}
+
+// parses a --check line, "DIGEST  NAME" or "DIGEST *NAME"
+func parse_check_line(line string) (check_entry, bool) {
+   sum_s, name, found := strings.Cut(line, " ")
+   if !found || len(name) < 2 || name[0] != ' ' && name[0] != '*' {
+      return check_entry{}, false
+   }
+   sum, ok := hex.DecodeString(sum_s)
+   if ok != nil {
+      return check_entry{}, false
+   }
+   if _, known := digest_for_size(len(sum)); !known {
+      return check_entry{}, false
+   }
+   return check_entry{sum, name[1:]}, true
+}
+
+// check_files reads each file named in the --check list through handle_file,
+// writing NAME: OK or NAME: FAILED for it, and says whether all were OK
+func check_files(cfg *Config, st *cat_state, out_stat *syscall.Stat_t, out_bSize int64) bool {
+   list, ok := os.Open(cfg.check)
+   if ok != nil {
+      print_error(cfg, ok)
+      return false
+   }
+   defer list.Close()
+
+   var entries []check_entry
+   n_improper := 0
+   scanner := bufio.NewScanner(list)
+   for scanner.Scan() {
+      if line := strings.TrimSuffix(scanner.Text(), "\r"); line != "" {
+         if entry, valid := parse_check_line(line); valid {
+            entries = append(entries, entry)
+         } else {
+            n_improper++
+         }
+      }
+   }
+   if ok = scanner.Err(); ok != nil {
+      print_error(cfg, ok)
+      return false
+   }
+   if n_improper > 0 {
+      fmt.Fprintf(os.Stderr, "cat: %s: WARNING: %d line(s) improperly formatted\n", cfg.check, n_improper)
+   }
+   if len(entries) == 0 {
+      fmt.Fprintf(os.Stderr, "cat: %s: no properly formatted checksum lines found\n", cfg.check)
+      return false
+   }
+
+   n_failed, n_unread := 0, 0
+   for _, entry := range entries {
+      if cfg.ctx != nil && cfg.ctx.Err() != nil {
+         break
+      }
+      algorithm, _ := digest_for_size(len(entry.sum))
+      h := digest_algorithms[algorithm]()
+      read := false
+      cfg.Report = func(src io.Reader, name string, blk_size int64) (string, error) {
+         sum, ok := digest(src, h, blk_size)
+         if ok != nil {
+            return "", ok
+         }
+         read = true
+         if !bytes.Equal(sum, entry.sum) {
+            n_failed++
+            return name + ": FAILED\n", nil
+         }
+         return name + ": OK\n", nil
+      }
+
+      if !handle_file(cfg, st, entry.name, out_stat, out_bSize) && !read {
+         n_unread++
+         if _, ok = st.write([]byte(entry.name + ": FAILED open or read\n")); ok != nil {
+            print_error(cfg, ok)
+            return false
+         }
+      }
+   }
+
+   if n_unread > 0 {
+      fmt.Fprintf(os.Stderr, "cat: WARNING: %d listed file(s) could not be read\n", n_unread)
+   }
+   if n_failed > 0 {
+      fmt.Fprintf(os.Stderr, "cat: WARNING: %d computed checksum(s) did NOT match\n", n_failed)
+   }
+   return n_failed == 0 && n_unread == 0
+}
@@ -0,0 +1,107 @@
+// Gotilities - cat
+// Author: prbrown
+//
+// --checkpoint and --resume, a long copy to a file that can be picked up
+// where it stopped rather than started over.
+package main
+
+import "os"
+import "fmt"
+import "time"
+import "strconv"
+import "strings"
+
+const CHECKPOINT_INTERVAL = time.Second
+
+// checkpoint_writer writes to the output file dst, and at most once an
+// interval records in the file at path how many bytes of it are known to be
+// on disk, counting from the n it started with
+type checkpoint_writer struct {
+   dst *os.File
+   path string
+   n int64
+   last time.Time
+}
+
+func new_checkpoint_writer(dst *os.File, path string, n int64) *checkpoint_writer {
+   return &checkpoint_writer{dst: dst, path: path, n: n, last: time.Now()}
+}
+
+func (w *checkpoint_writer) Write(p []byte) (int, error) {
+   n_written, ok := w.dst.Write(p)
+   w.n += int64(n_written)
+   if ok != nil {
+      return n_written, ok
+   }
+   if now := time.Now(); now.Sub(w.last) >= CHECKPOINT_INTERVAL {
+      w.last = now
+      ok = w.save()
+   }
+   return n_written, ok
+}
+
+// records the count once what it counts is synced, and by a rename so that
+// the file at path is never half written
+func (w *checkpoint_writer) save() error {
+   if ok := w.dst.Sync(); ok != nil {
+      return ok
+   }
+   tmp := w.path + ".tmp"
+   if ok := os.WriteFile(tmp, []byte(strconv.FormatInt(w.n, 10) + "\n"), 0666); ok != nil {
+      return ok
+   }
+   return os.Rename(tmp, w.path)
+}
+
+// the count of bytes a checkpoint file records, 0 when there is none yet
+func read_checkpoint(path string) (int64, error) {
+   data, ok := os.ReadFile(path)
+   if os.IsNotExist(ok) {
+      return 0, nil
+   } else if ok != nil {
+      return 0, ok
+   }
+   n, ok := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+   if ok != nil || n < 0 {
+      return 0, fmt.Errorf("%s: not a checkpoint", path)
+   }
+   return n, nil
+}
+
+// whether cfg writes its input as it is, with nothing added, left out or
+// changed, so that the bytes of output a checkpoint counts are as far into
+// the input
+func (cfg *Config) plain_copy() bool {
+   o := cfg.Options
+   transforms := o.Number || o.NumberNonblank || o.ShowEnds || o.ShowNonprinting || o.ShowTabs || o.SqueezeBlank || o.SqueezeAll || o.TrimTrailing || o.StripCR || o.LineEnding != "" || o.Translate != "" || o.Delete != "" || o.SqueezeRepeats != "" || o.AsciiOnly != ""
+   modes := cfg.Mode != nil || cfg.Report != nil || cfg.Filter != nil || cfg.fold_width > 0 || cfg.shuffle || cfg.expand || cfg.unexpand || cfg.uniq || cfg.seq != nil || cfg.yes != nil || cfg.merge || cfg.join || cfg.check != "" || cfg.list_files || cfg.count_only
+   filters := cfg.match != nil || cfg.number_match != nil || cfg.lines_from > 0 || cfg.collapse || cfg.prefix != "" || cfg.suffix != "" || cfg.json || cfg.fields != nil || cfg.chars != nil || cfg.bytes != nil
+   reshapes := cfg.headers || cfg.decompress || cfg.strip_bom || cfg.ensure_newline || cfg.repeat > 1 || cfg.trim_head > 0 || cfg.trim_tail > 0 || cfg.break_long == "break"
+   return !transforms && !modes && !filters && !reshapes
+}
+
+// resume_output readies the output file of cfg to carry on from the
+// checkpoint: cut back to the bytes recorded, which may be fewer than were
+// written, and appended to from as far into the input. An output shorter
+// than the checkpoint is not the one it was made for, and fails.
+func resume_output(cfg *Config) (int64, error) {
+   n, ok := read_checkpoint(cfg.checkpoint)
+   if ok != nil {
+      return 0, ok
+   }
+   if n > 0 {
+      info, ok := os.Stat(cfg.Output)
+      if ok != nil {
+         return 0, ok
+      }
+      if info.Size() < n {
+         return 0, fmt.Errorf("%s: shorter than the %d bytes %s records", cfg.Output, n, cfg.checkpoint)
+      }
+      if ok = os.Truncate(cfg.Output, n); ok != nil {
+         return 0, ok
+      }
+   }
+   cfg.Options.StartOffset += n
+   cfg.Append = true
+   return n, nil
+}
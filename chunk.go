@@ -0,0 +1,91 @@
+// Gotilities - split
+// Author: prbrown
+//
+// --chunk-lines and --chunk-bytes, the output split into files of so many
+// lines, or bytes, each.
+package main
+
+import "os"
+import "fmt"
+import "bytes"
+
+// chunk_writer writes to the files PRE000, PRE001 and so on, lines lines to
+// each or, when lines is 0, size bytes, a line split across two files if it
+// must. A file is created with the first byte it gets, so no empty one is
+// left after the last.
+type chunk_writer struct {
+   prefix string
+   lines int64 // to a file
+   size int64  // to a file, when lines is 0
+   sep byte    // ends each line
+   f *os.File  // the file being written, nil between files
+   index int   // of the next file
+   n_lines int64 // written to f
+   n_bytes int64
+}
+
+func new_chunk_writer(prefix string, lines int64, size int64, sep byte) *chunk_writer {
+   return &chunk_writer{prefix: prefix, lines: lines, size: size, sep: sep}
+}
+
+// the name of the file numbered index
+func chunk_name(prefix string, index int) string {
+   return fmt.Sprintf("%s%03d", prefix, index)
+}
+
+func (c *chunk_writer) Write(p []byte) (int, error) {
+   n_written := 0
+   for len(p) > 0 {
+      if c.f == nil {
+         f, ok := os.OpenFile(chunk_name(c.prefix, c.index), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+         if ok != nil {
+            return n_written, ok
+         }
+         c.f = f
+         c.index++
+      }
+
+      // the bytes up to the end of the file's last line or byte, or all of p
+      n := 0
+      if c.lines == 0 {
+         n = int(min(int64(len(p)), c.size - c.n_bytes))
+      }
+      for c.n_lines < c.lines {
+         i := bytes.IndexByte(p[n:], c.sep)
+         if i < 0 {
+            n = len(p)
+            break
+         }
+         n += i+1
+         c.n_lines++
+      }
+
+      n_file, ok := c.f.Write(p[:n])
+      n_written += n_file
+      c.n_bytes += int64(n_file)
+      if ok != nil {
+         return n_written, ok
+      }
+      p = p[n:]
+
+      if c.lines > 0 && c.n_lines == c.lines || c.lines == 0 && c.n_bytes == c.size {
+         c.n_lines, c.n_bytes = 0, 0
+         ok = c.f.Close()
+         c.f = nil
+         if ok != nil {
+            return n_written, ok
+         }
+      }
+   }
+   return n_written, nil
+}
+
+// Close closes the file being written, if any
+func (c *chunk_writer) Close() error {
+   if c.f == nil {
+      return nil
+   }
+   ok := c.f.Close()
+   c.f = nil
+   return ok
+}
@@ -0,0 +1,77 @@
+// Gotilities - cksum
+// Author: prbrown
+//
+// The POSIX cksum CRC and byte count of the input.
+//
+// Developed using the following source code as reference:
+// [1] https://pubs.opengroup.org/onlinepubs/9699919799/utilities/cksum.html
+package main
+
+import "io"
+import "fmt"
+
+// the CRC-32 polynomial of [1], taken most significant bit first, unlike
+// hash/crc32
+const CKSUM_POLY uint32 = 0x04C11DB7
+
+var cksum_table = make_cksum_table()
+
+func make_cksum_table() (table [256]uint32) {
+   for i := range table {
+      crc := uint32(i) << 24
+      for bit := 0; bit < 8; bit++ {
+         if crc & 0x80000000 != 0 {
+            crc = crc << 1 ^ CKSUM_POLY
+         } else {
+            crc <<= 1
+         }
+      }
+      table[i] = crc
+   }
+   return table
+}
+
+func cksum_update(crc uint32, p []byte) uint32 {
+   for _, ch := range p {
+      crc = crc << 8 ^ cksum_table[byte(crc >> 24) ^ ch]
+   }
+   return crc
+}
+
+// Cksum returns the checksum of src as printed by POSIX cksum, and its
+// length in bytes.
+func Cksum(src io.Reader) (crc uint32, bytes int64, err error) {
+   return cksum(src, IO_BLK_SIZE_DEFAULT)
+}
+
+func cksum(src io.Reader, blk_size int64) (crc uint32, bytes int64, err error) {
+   buf := make([]byte, blk_size)
+
+   for ;; {
+      n_read, ok := src.Read(buf)
+      crc = cksum_update(crc, buf[:n_read])
+      bytes += int64(n_read)
+
+      if ok == io.EOF {
+         break
+      } else if ok != nil {
+         return 0, bytes, ok
+      }
+   }
+
+   // the length follows the data, least significant byte first and without
+   // the high zero bytes
+   for n := bytes; n > 0; n >>= 8 {
+      crc = cksum_update(crc, []byte{byte(n)})
+   }
+   return ^crc, bytes, nil
+}
+
+// --cksum, one cksum style line per file, with no name for stdin as cksum
+func cksum_report(src io.Reader, name string, blk_size int64) (string, error) {
+   crc, bytes, ok := cksum(src, blk_size)
+   if name == "-" {
+      return fmt.Sprintf("%d %d\n", crc, bytes), ok
+   }
+   return fmt.Sprintf("%d %d %s\n", crc, bytes, name), ok
+}
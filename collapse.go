@@ -0,0 +1,23 @@
+// Gotilities - collapse
+// Author: prbrown
+//
+// --collapse-whitespace, each line trimmed of its blanks at either end and
+// each run of them inside it made one space.
+package main
+
+// appends line to dst with its blanks, spaces and tabs, collapsed
+func collapse_blanks(dst []byte, line []byte) []byte {
+   blank := false
+   for _, ch := range line {
+      if ch == ' ' || ch == '\t' {
+         blank = true
+         continue
+      }
+      if blank && len(dst) > 0 {
+         dst = append(dst, ' ')
+      }
+      blank = false
+      dst = append(dst, ch)
+   }
+   return dst
+}
@@ -0,0 +1,62 @@
+// Gotilities - cat
+// Author: prbrown
+//
+// --color, line numbers and escapes set off in ANSI colors, and the -v escape
+// every styled escape goes through, --summary counting as it goes.
+package main
+
+import "strings"
+import "strconv"
+
+const ANSI_NUMBER string = "\033[2m"  // dim
+const ANSI_ESCAPE string = "\033[35m" // magenta
+const ANSI_RESET string = "\033[0m"
+
+// escape is -v notation, colored with Options.Color when ch does get
+// escaped, with a tab shown as Options.TabString when one is set
+func (st *cat_state) escape(dst []byte, ch byte) []byte {
+   if !st.opts.Color || (ch >= ' ' && ch < 0x7F) {
+      return st.escape_plain(dst, ch)
+   }
+   dst = append(dst, ANSI_ESCAPE...)
+   dst = st.escape_plain(dst, ch)
+   return append(dst, ANSI_RESET...)
+}
+
+func (st *cat_state) escape_plain(dst []byte, ch byte) []byte {
+   if st.escape_counts != nil && (ch < ' ' || ch >= 0x7F) {
+      st.escape_counts[ch]++ // (--summary)
+   }
+   if ch == '\t' && st.opts.TabString != "" {
+      return append(dst, st.opts.TabString...)
+   }
+   return st.escape_byte(dst, ch)
+}
+
+// the most bytes escape writes for one byte, for sizing buffers
+func (st *cat_state) max_escape() int {
+   n := max(len("M-^?"), len(st.opts.TabString))
+   if st.opts.Color {
+      n += len(ANSI_ESCAPE) + len(ANSI_RESET)
+   }
+   return n
+}
+
+// the --summary line of counts, as "NUL: 12, ^M: 3", in byte order
+func escape_summary(counts *[256]int64) string {
+   var parts []string
+   for ch, n := range counts {
+      if n == 0 {
+         continue
+      }
+      name := "NUL"
+      if ch != 0 {
+         name = string(EscapeNonPrinting(nil, byte(ch)))
+      }
+      parts = append(parts, name + ": " + strconv.FormatInt(n, 10))
+   }
+   if parts == nil {
+      return "no nonprinting bytes"
+   }
+   return strings.Join(parts, ", ")
+}
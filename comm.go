@@ -0,0 +1,107 @@
+// Gotilities - comm
+// Author: prbrown
+//
+// Compare two sorted inputs line by line, in three columns.
+package main
+
+import "io"
+import "bufio"
+import "bytes"
+
+// the columns of Comm that CommHide leaves out, as comm -1, -2 and -3
+const (
+   CommHideA = 1 << iota // lines only in a
+   CommHideB             // lines only in b
+   CommHideBoth          // lines in both
+)
+
+// Comm writes to dst the lines of a and b, each sorted byte by byte, in
+// three columns as comm does: lines only in a, lines only in b indented by a
+// tab, and lines in both by two. Every line written ends in a newline.
+func Comm(dst io.Writer, a io.Reader, b io.Reader) error {
+   return comm(dst, a, b, 0, IO_BLK_SIZE_DEFAULT)
+}
+
+// CommHide is Comm without the columns in hide, CommHideA, CommHideB and
+// CommHideBoth or'd together; the columns left are indented as if the
+// others were not there.
+func CommHide(dst io.Writer, a io.Reader, b io.Reader, hide int) error {
+   return comm(dst, a, b, hide, IO_BLK_SIZE_DEFAULT)
+}
+
+func comm(dst io.Writer, a io.Reader, b io.Reader, hide int, blk_size int64) error {
+   // the tabs before each column, one for each shown column before it
+   var indents [3][]byte
+   var tabs []byte
+   for col := 0; col < 3; col++ {
+      if hide & (1 << col) == 0 {
+         indents[col] = tabs
+         tabs = append(bytes.Clone(tabs), '\t')
+      }
+   }
+
+   ls_a, ls_b := new_line_scanner(a, blk_size), new_line_scanner(b, blk_size)
+   out := bufio.NewWriterSize(dst, int(blk_size))
+   line_a, ok := comm_next(ls_a)
+   if ok != nil {
+      return ok
+   }
+   line_b, ok := comm_next(ls_b)
+   if ok != nil {
+      return ok
+   }
+
+   for line_a != nil || line_b != nil {
+      col := 2
+      switch {
+      case line_b == nil:
+         col = 0
+      case line_a == nil:
+         col = 1
+      default:
+         if c := bytes.Compare(line_a, line_b); c < 0 {
+            col = 0
+         } else if c > 0 {
+            col = 1
+         }
+      }
+
+      line := line_a
+      if col == 1 {
+         line = line_b
+      }
+      if hide & (1 << col) == 0 {
+         out.Write(indents[col])
+         out.Write(line)
+         if ok = out.WriteByte('\n'); ok != nil {
+            return ok
+         }
+      }
+
+      if col != 1 {
+         if line_a, ok = comm_next(ls_a); ok != nil {
+            out.Flush()
+            return ok
+         }
+      }
+      if col != 0 {
+         if line_b, ok = comm_next(ls_b); ok != nil {
+            out.Flush()
+            return ok
+         }
+      }
+   }
+   return out.Flush()
+}
+
+// the next line of ls without its newline, nil at the end; it is a copy, as
+// it may be held while the other input is read
+func comm_next(ls *line_scanner) ([]byte, error) {
+   line, ok := ls.next()
+   if ok == io.EOF {
+      return nil, nil
+   } else if ok != nil {
+      return nil, ok
+   }
+   return bytes.Clone(bytes.TrimSuffix(line, []byte{'\n'})), nil
+}
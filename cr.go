@@ -0,0 +1,109 @@
+// Gotilities - cat
+// Author: prbrown
+//
+// --strip-cr and --line-ending, CRLF and CR line endings read as plain
+// newlines, and newlines written as CRLF.
+package main
+
+import "io"
+import "bytes"
+
+// cr_reader drops each CR that comes just before a LF, and makes any other
+// CR the byte lone, '\n' to read it as a line ending or '\r' to leave it. A
+// CR ending one read is held back until the next byte shows whether a LF
+// follows it.
+type cr_reader struct {
+   src io.Reader
+   lone byte
+   held bool
+}
+
+// the input of opts, given its line endings
+func new_cr_reader(src io.Reader, opts Options) io.Reader {
+   if opts.LineEnding != "" {
+      return &cr_reader{src: src, lone: '\n'}
+   } else if opts.StripCR {
+      return &cr_reader{src: src, lone: '\r'}
+   }
+   return src
+}
+
+func (r *cr_reader) Read(p []byte) (int, error) {
+   if len(p) == 0 {
+      return 0, nil
+   }
+
+   for ;; {
+      start := 0
+      if r.held {
+         start = 1 // p[0] is kept for the held CR
+      }
+      n_read, ok := r.src.Read(p[start:])
+
+      if r.held {
+         if n_read == 0 {
+            if ok == nil {
+               continue
+            }
+            p[0] = r.lone // the input ends on it
+            r.held = false
+            return 1, nil
+         }
+         r.held = false
+         if p[1] == '\n' {
+            copy(p, p[1:1+n_read])
+         } else {
+            p[0] = r.lone
+            n_read++
+         }
+      }
+
+      // drop the CR of each CRLF, in place
+      n := 0
+      for i := 0; i < n_read; i++ {
+         if p[i] == '\r' {
+            if i+1 == n_read && ok == nil {
+               r.held = true
+               break
+            }
+            if i+1 < n_read && p[i+1] == '\n' {
+               continue
+            }
+            p[i] = r.lone
+         }
+         p[n] = p[i]
+         n++
+      }
+
+      if n > 0 || ok != nil {
+         return n, ok
+      }
+   }
+}
+
+// appends src to dst as a cr_reader reads it, the whole of the input
+func from_cr(dst []byte, src []byte, lone byte) []byte {
+   for ;; {
+      i := bytes.IndexByte(src, '\r')
+      if i < 0 {
+         return append(dst, src...)
+      }
+      dst = append(dst, src[:i]...)
+      if i+1 == len(src) || src[i+1] != '\n' {
+         dst = append(dst, lone)
+      }
+      src = src[i+1:]
+   }
+}
+
+// appends src to dst with each LF made CRLF
+func to_crlf(dst []byte, src []byte) []byte {
+   for ;; {
+      i := bytes.IndexByte(src, '\n')
+      if i < 0 {
+         return append(dst, src...)
+      }
+      dst = append(append(dst, src[:i]...), '\r', '\n')
+      src = src[i+1:]
+   }
+}
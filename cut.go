@@ -0,0 +1,106 @@
+// Gotilities - cut
+// Author: prbrown
+//
+// --fields, --chars and --bytes, the parts of each line between a delimiter,
+// or its characters or bytes, picked out by their place, as cut.
+package main
+
+import "bytes"
+import "errors"
+import "strconv"
+import "strings"
+import "unicode/utf8"
+
+// a range of the places, from 1, in a --fields, --chars or --bytes LIST; hi
+// is 0 for no end
+type list_range struct {
+   lo int
+   hi int
+}
+
+var err_invalid_list = errors.New("invalid list")
+
+// parses a LIST as cut takes it, ranges N, N-M, N- and -M between commas
+func parse_list(s string) ([]list_range, error) {
+   var list []list_range
+   for _, part := range strings.Split(s, ",") {
+      lo_s, hi_s, is_range := strings.Cut(part, "-")
+      if !is_range {
+         hi_s = lo_s
+      }
+
+      r := list_range{1, 0}
+      var ok error
+      if lo_s != "" {
+         if r.lo, ok = strconv.Atoi(lo_s); ok != nil || r.lo < 1 {
+            return nil, err_invalid_list
+         }
+      } else if !is_range {
+         return nil, err_invalid_list // an empty part
+      }
+      if hi_s != "" {
+         if r.hi, ok = strconv.Atoi(hi_s); ok != nil || r.hi < r.lo {
+            return nil, err_invalid_list
+         }
+      } else if lo_s == "" {
+         return nil, err_invalid_list // a lone -
+      }
+      list = append(list, r)
+   }
+   return list, nil
+}
+
+// whether place i is in list
+func in_list(list []list_range, i int) bool {
+   for _, r := range list {
+      if i >= r.lo && (r.hi == 0 || i <= r.hi) {
+         return true
+      }
+   }
+   return false
+}
+
+// appends the fields of line in list to dst, in the order of the line and
+// with delim between them; false, and dst as it was, when line has no delim
+func cut_fields(dst []byte, line []byte, list []list_range, delim byte) ([]byte, bool) {
+   if bytes.IndexByte(line, delim) < 0 {
+      return dst, false
+   }
+   start := len(dst)
+   for i := 1; ; i++ {
+      field, rest, more := bytes.Cut(line, []byte{delim})
+      if in_list(list, i) {
+         if len(dst) > start {
+            dst = append(dst, delim)
+         }
+         dst = append(dst, field...)
+      }
+      if !more {
+         return dst, true
+      }
+      line = rest
+   }
+}
+
+// appends the bytes of line in list to dst
+func cut_bytes(dst []byte, line []byte, list []list_range) []byte {
+   for i, ch := range line {
+      if in_list(list, i+1) {
+         dst = append(dst, ch)
+      }
+   }
+   return dst
+}
+
+// appends the characters of line in list to dst, each UTF-8 sequence one
+// character and each byte that is not in one a character of its own
+func cut_chars(dst []byte, line []byte, list []list_range) []byte {
+   for i := 1; len(line) > 0; i++ {
+      _, size := utf8.DecodeRune(line)
+      if in_list(list, i) {
+         dst = append(dst, line[:size]...)
+      }
+      line = line[size:]
+   }
+   return dst
+}
@@ -0,0 +1,49 @@
+// Gotilities - zcat, bzcat
+// Author: prbrown
+//
+// --decompress, compressed input written as its uncompressed data.
+package main
+
+import "io"
+import "bytes"
+import "errors"
+import "compress/gzip"
+import "compress/bzip2"
+
+// the formats --decompress knows, by the bytes their data starts with. A nil
+// open is a format recognised but not supported.
+var compressed_formats = []struct {
+   name string
+   magic []byte
+   open func(io.Reader) (io.Reader, error)
+}{
+   {"gzip", []byte{0x1F, 0x8B}, func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+   {"bzip2", []byte("BZh"), func(r io.Reader) (io.Reader, error) { return bzip2.NewReader(r), nil }},
+   {"zstd", []byte{0x28, 0xB5, 0x2F, 0xFD}, nil},
+}
+
+// the longest magic of compressed_formats
+const COMPRESSED_MAGIC_MAX = 4
+
+var err_not_compressed = errors.New("not in a known compressed format")
+
+// decompress_reader returns the uncompressed data of src, its format told
+// apart by its first bytes, which are peeked rather than read away
+func decompress_reader(src io.Reader, blk_size int64) (io.Reader, error) {
+   rd := new_peek_reader(src, blk_size)
+   magic, ok := rd.peek(COMPRESSED_MAGIC_MAX)
+   if ok != nil && ok != io.EOF {
+      return nil, ok
+   }
+
+   for _, format := range compressed_formats {
+      if !bytes.HasPrefix(magic, format.magic) {
+         continue
+      }
+      if format.open == nil {
+         return nil, errors.New(format.name + " compression not supported")
+      }
+      return format.open(rd)
+   }
+   return nil, err_not_compressed
+}
@@ -0,0 +1,27 @@
+// Gotilities - cat
+// Author: prbrown
+//
+// Device inputs, as /dev/zero or /dev/urandom, which never end, read only
+// with --max-bytes or --device-ok.
+package main
+
+import "os"
+import "syscall"
+
+// whether fDes, of in_stat, is a character or block device other than a
+// terminal or /dev/null, the devices cat is used on as a matter of course
+func is_device(fDes *os.File, in_stat *syscall.Stat_t) bool {
+   switch in_stat.Mode & syscall.S_IFMT {
+      case syscall.S_IFCHR, syscall.S_IFBLK:
+      default:
+         return false
+   }
+   if is_terminal(fDes) {
+      return false
+   }
+   var null_stat syscall.Stat_t
+   if syscall.Stat(os.DevNull, &null_stat) == nil && null_stat.Rdev == in_stat.Rdev {
+      return false
+   }
+   return true
+}
@@ -0,0 +1,46 @@
+// Gotilities - sha256sum, sha1sum, md5sum
+// Author: prbrown
+//
+// Message digests of the input, printed as the coreutils *sum tools do.
+package main
+
+import "io"
+import "fmt"
+import "hash"
+import "crypto/md5"
+import "crypto/sha1"
+import "crypto/sha256"
+
+// the --digest algorithms
+var digest_algorithms = map[string]func() hash.Hash{
+   "md5": md5.New,
+   "sha1": sha1.New,
+   "sha256": sha256.New,
+}
+
+// Digest returns the sum of src under h, which is reset first.
+func Digest(src io.Reader, h hash.Hash) ([]byte, error) {
+   return digest(src, h, IO_BLK_SIZE_DEFAULT)
+}
+
+func digest(src io.Reader, h hash.Hash, blk_size int64) ([]byte, error) {
+   h.Reset()
+   if _, ok := io.CopyBuffer(h, src, make([]byte, blk_size)); ok != nil {
+      return nil, ok
+   }
+   return h.Sum(nil), nil
+}
+
+// --digest=ALGORITHM, one coreutils style line per file
+func digest_report(algorithm string) (func(src io.Reader, name string, blk_size int64) (string, error), error) {
+   new_hash, found := digest_algorithms[algorithm]
+   if !found {
+      return nil, fmt.Errorf("invalid argument '%s' for '--digest'", algorithm)
+   }
+
+   h := new_hash()
+   return func(src io.Reader, name string, blk_size int64) (string, error) {
+      sum, ok := digest(src, h, blk_size)
+      return fmt.Sprintf("%x  %s\n", sum, name), ok
+   }, nil
+}
@@ -0,0 +1,249 @@
+// Gotilities - expand, unexpand
+// Author: prbrown
+//
+// Convert tabs to spaces and back, following GNU expand and unexpand.
+//
+// Developed using the following source code as reference:
+// [1] http://git.savannah.gnu.org/cgit/coreutils.git/plain/src/expand.c
+// [2] http://git.savannah.gnu.org/cgit/coreutils.git/plain/src/unexpand.c
+package main
+
+import "io"
+import "bufio"
+import "errors"
+import "fmt"
+import "strconv"
+import "strings"
+
+// where tabs stop: every size columns, or at the columns in list
+type tab_stops struct {
+   size int
+   list []int
+}
+
+// tabStops is one width for uniform stops or the ascending columns of each
+// stop, none meaning every 8 columns
+func new_tab_stops(tabStops []int) (tab_stops, error) {
+   if len(tabStops) == 0 {
+      return tab_stops{size: TAB_WIDTH}, nil
+   }
+   if len(tabStops) == 1 {
+      if tabStops[0] < 1 {
+         return tab_stops{}, errors.New("tab size must be positive")
+      }
+      return tab_stops{size: tabStops[0]}, nil
+   }
+
+   prev := -1
+   for _, stop := range tabStops {
+      if stop <= prev {
+         return tab_stops{}, errors.New("tab sizes must be ascending")
+      }
+      prev = stop
+   }
+   return tab_stops{list: tabStops}, nil
+}
+
+// next returns the column of the tab stop after column, and true once past
+// the last stop of a list. tab_index remembers the place in the list.
+func (t tab_stops) next(column int, tab_index *int) (int, bool) {
+   if t.size > 0 {
+      return column + t.size - column % t.size, false
+   }
+   for ; *tab_index < len(t.list); *tab_index++ {
+      if column < t.list[*tab_index] {
+         return t.list[*tab_index], false
+      }
+   }
+   return 0, true
+}
+
+// Expand writes src to dst with each tab replaced by spaces up to the next
+// tab stop. A tab past the last stop of a list becomes one space.
+func Expand(dst io.Writer, src io.Reader, tabStops []int) error {
+   return expand(dst, src, tabStops, IO_BLK_SIZE_DEFAULT)
+}
+
+func expand(dst io.Writer, src io.Reader, tabStops []int, blk_size int64) error {
+   stops, ok := new_tab_stops(tabStops)
+   if ok != nil {
+      return ok
+   }
+
+   rd := bufio.NewReaderSize(src, int(blk_size))
+   out := bufio.NewWriterSize(dst, int(blk_size))
+   column := 0
+   tab_index := 0
+
+   for ;; {
+      ch, ok := rd.ReadByte()
+      if ok == io.EOF {
+         return out.Flush()
+      } else if ok != nil {
+         out.Flush()
+         return ok
+      }
+
+      switch ch {
+      case '\t':
+         next_tab_column, last_tab := stops.next(column, &tab_index)
+         if last_tab {
+            next_tab_column = column+1
+         }
+         for ; column < next_tab_column; column++ {
+            out.WriteByte(' ')
+         }
+         continue
+      case '\b':
+         if column > 0 {
+            column--
+         }
+         if tab_index > 0 {
+            tab_index--
+         }
+      case '\n':
+         column = 0
+         tab_index = 0
+      default:
+         column++
+      }
+
+      if ok := out.WriteByte(ch); ok != nil {
+         return ok
+      }
+   }
+}
+
+// Unexpand writes src to dst with runs of blanks at the start of each line
+// replaced by tabs where they reach a tab stop, or every such run when
+// allBlanks is set.
+func Unexpand(dst io.Writer, src io.Reader, tabStops []int, allBlanks bool) error {
+   return unexpand(dst, src, tabStops, allBlanks, IO_BLK_SIZE_DEFAULT)
+}
+
+// a line at a time port of [2]
+func unexpand(dst io.Writer, src io.Reader, tabStops []int, all_blanks bool, blk_size int64) error {
+   stops, ok := new_tab_stops(tabStops)
+   if ok != nil {
+      return ok
+   }
+
+   rd := bufio.NewReaderSize(src, int(blk_size))
+   out := bufio.NewWriterSize(dst, int(blk_size))
+
+   for ;; {
+      // per line state
+      convert := true
+      column := 0
+      next_tab_column := 0
+      tab_index := 0
+      one_blank_before_tab_stop := false
+      prev_blank := true
+      var pending_blank []byte
+
+      for ;; {
+         ch, ok := rd.ReadByte()
+         eof := ok == io.EOF
+         if ok != nil && !eof {
+            out.Flush()
+            return ok
+         }
+
+         if convert && !eof {
+            blank := ch == ' ' || ch == '\t'
+
+            if blank {
+               var last_tab bool
+               next_tab_column, last_tab = stops.next(column, &tab_index)
+               if last_tab {
+                  convert = false
+               }
+            }
+
+            if blank && convert {
+               if ch == '\t' {
+                  column = next_tab_column
+                  if len(pending_blank) > 0 {
+                     pending_blank[0] = '\t'
+                  }
+               } else {
+                  column++
+                  if !(prev_blank && column == next_tab_column) {
+                     // not yet known whether these become a tab
+                     if column == next_tab_column {
+                        one_blank_before_tab_stop = true
+                     }
+                     pending_blank = append(pending_blank, ch)
+                     prev_blank = true
+                     continue
+                  }
+
+                  // the pending blanks reach a stop, make them a tab
+                  ch = '\t'
+                  if len(pending_blank) > 0 {
+                     pending_blank[0] = '\t'
+                  }
+               }
+
+               // drop the pending blanks, unless it was one blank just
+               // before the previous tab stop
+               if one_blank_before_tab_stop {
+                  pending_blank = pending_blank[:1]
+               } else {
+                  pending_blank = pending_blank[:0]
+               }
+            } else if ch == '\b' {
+               if column > 0 {
+                  column--
+               }
+               next_tab_column = column
+               if tab_index > 0 {
+                  tab_index--
+               }
+            } else if !blank {
+               column++
+            }
+
+            if len(pending_blank) > 0 {
+               if len(pending_blank) > 1 && one_blank_before_tab_stop {
+                  pending_blank[0] = '\t'
+               }
+               out.Write(pending_blank)
+               pending_blank = pending_blank[:0]
+               one_blank_before_tab_stop = false
+            }
+
+            prev_blank = blank
+            convert = convert && (all_blanks || blank)
+         }
+
+         if eof {
+            out.Write(pending_blank)
+            return out.Flush()
+         }
+
+         if ok := out.WriteByte(ch); ok != nil {
+            return ok
+         }
+         if ch == '\n' {
+            break
+         }
+      }
+   }
+}
+
+// --tabs=LIST, stops separated by commas or blanks
+func parse_tab_list(s string) ([]int, error) {
+   var stops []int
+   for _, field := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+      stop, ok := strconv.Atoi(field)
+      if ok != nil || stop < 1 {
+         return nil, fmt.Errorf("tab size contains invalid character(s): '%s'", field)
+      }
+      stops = append(stops, stop)
+   }
+   if _, ok := new_tab_stops(stops); ok != nil {
+      return nil, ok
+   }
+   return stops, nil
+}
@@ -0,0 +1,51 @@
+// Gotilities - cat
+// Author: prbrown
+//
+// --files-from, the FILEs to read listed in a file, as tar takes them.
+package main
+
+import "os"
+import "io"
+import "bytes"
+
+// read_file_list reads the names in list, "-" for standard input, each ended
+// by sep; empty names are passed over
+func read_file_list(list string, sep byte) ([]string, error) {
+   var data []byte
+   var ok error
+   if list == "-" {
+      data, ok = io.ReadAll(os.Stdin)
+   } else {
+      data, ok = os.ReadFile(list)
+   }
+   if ok != nil {
+      return nil, ok
+   }
+
+   var names []string
+   for _, name := range bytes.Split(data, []byte{sep}) {
+      if len(name) > 0 {
+         names = append(names, string(name))
+      }
+   }
+   return names, nil
+}
+
+// insert_files puts names into cfg.Files at index at, the FILEs and --fd
+// descriptors after it moving along
+func insert_files(cfg *Config, at int, names []string) {
+   files := append([]string{}, cfg.Files[:at]...)
+   files = append(files, names...)
+   cfg.Files = append(files, cfg.Files[at:]...)
+
+   if len(cfg.fds) > 0 {
+      fds := make(map[int]int, len(cfg.fds))
+      for i, fd := range cfg.fds {
+         if i >= at {
+            i += len(names)
+         }
+         fds[i] = fd
+      }
+      cfg.fds = fds
+   }
+}
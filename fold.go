@@ -0,0 +1,119 @@
+// Gotilities - fold
+// Author: prbrown
+//
+// Wrap input lines to fit a given width, as GNU fold does.
+package main
+
+import "io"
+import "bufio"
+
+const TAB_WIDTH int = 8
+
+// Fold writes src to dst with lines longer than width columns broken in
+// two. A tab moves to the next multiple of 8 columns, a backspace moves back
+// one and a carriage return goes to column 0. With breakAtSpaces a line is
+// broken after its last blank that fits, if it has one.
+func Fold(dst io.Writer, src io.Reader, width int, breakAtSpaces bool) error {
+   return fold(dst, src, width, breakAtSpaces, false, IO_BLK_SIZE_DEFAULT)
+}
+
+// FoldBytes is Fold counting every byte, tab and backspace included, as one
+// column.
+func FoldBytes(dst io.Writer, src io.Reader, width int, breakAtSpaces bool) error {
+   return fold(dst, src, width, breakAtSpaces, true, IO_BLK_SIZE_DEFAULT)
+}
+
+// column after ch is written at column
+func fold_column(column int, ch byte, count_bytes bool) int {
+   if count_bytes {
+      return column+1
+   }
+
+   switch ch {
+   case '\b':
+      if column > 0 {
+         return column-1
+      }
+      return 0
+   case '\r':
+      return 0
+   case '\t':
+      return column + TAB_WIDTH - column % TAB_WIDTH
+   }
+   return column+1
+}
+
+func fold(dst io.Writer, src io.Reader, width int, break_spaces bool, count_bytes bool, blk_size int64) error {
+   if width < 1 {
+      width = 1
+   }
+
+   rd := bufio.NewReaderSize(src, int(blk_size))
+   out := bufio.NewWriterSize(dst, int(blk_size))
+   var line_out []byte // current output line, short of its newline
+   column := 0
+
+   for ;; {
+      ch, ok := rd.ReadByte()
+      if ok == io.EOF {
+         out.Write(line_out)
+         return out.Flush()
+      } else if ok != nil {
+         out.Flush()
+         return ok
+      }
+
+      if ch == '\n' {
+         line_out = append(line_out, '\n')
+         if _, ok := out.Write(line_out); ok != nil {
+            return ok
+         }
+         line_out = line_out[:0]
+         column = 0
+         continue
+      }
+
+      for ;; {
+         column = fold_column(column, ch, count_bytes)
+         if column <= width {
+            line_out = append(line_out, ch)
+            break
+         }
+
+         if break_spaces {
+            // break after the last blank, carry the rest to the next line
+            logical_end := len(line_out)-1
+            for logical_end >= 0 && line_out[logical_end] != ' ' && line_out[logical_end] != '\t' {
+               logical_end--
+            }
+            if logical_end >= 0 {
+               logical_end++
+               out.Write(line_out[:logical_end])
+               if ok := out.WriteByte('\n'); ok != nil {
+                  return ok
+               }
+               line_out = line_out[:copy(line_out, line_out[logical_end:])]
+
+               column = 0
+               for _, c := range line_out {
+                  column = fold_column(column, c, count_bytes)
+               }
+               continue
+            }
+         }
+
+         // a single too wide character still gets a line of its own
+         if len(line_out) == 0 {
+            line_out = append(line_out, ch)
+            break
+         }
+
+         line_out = append(line_out, '\n')
+         if _, ok := out.Write(line_out); ok != nil {
+            return ok
+         }
+         line_out = line_out[:0]
+         column = 0
+      }
+   }
+}
@@ -0,0 +1,57 @@
+// Gotilities - head
+// Author: prbrown
+//
+// Output the first part of the input.
+package main
+
+import "io"
+import "bytes"
+
+// Head writes the first n lines of src to dst, or the first n bytes when
+// byteMode is set. Reading stops once the limit is reached, so the rest of a
+// pipe is left unread.
+func Head(dst io.Writer, src io.Reader, n int, byteMode bool) error {
+   return head(dst, src, n, byteMode, '\n', IO_BLK_SIZE_DEFAULT)
+}
+
+// head is Head of the lines ending with sep
+func head(dst io.Writer, src io.Reader, n int, byte_mode bool, sep byte, blk_size int64) error {
+   if n <= 0 {
+      return nil
+   }
+
+   buf := make([]byte, blk_size)
+   if byte_mode {
+      _, ok := io.CopyBuffer(dst, io.LimitReader(src, int64(n)), buf)
+      return ok
+   }
+
+   for ;; {
+      n_read, ok := src.Read(buf)
+      if ok != nil && ok != io.EOF {
+         return ok
+      }
+
+      // end of the n-th line, or all of buf
+      end := 0
+      for n > 0 && end < n_read {
+         i := bytes.IndexByte(buf[end:n_read], sep)
+         if i < 0 {
+            end = n_read
+            break
+         }
+         end += i+1
+         n--
+      }
+
+      if end > 0 {
+         if _, ok := dst.Write(buf[:end]); ok != nil {
+            return ok
+         }
+      }
+
+      if n == 0 || ok == io.EOF {
+         return nil
+      }
+   }
+}
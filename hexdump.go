@@ -0,0 +1,97 @@
+// Gotilities - hexdump
+// Author: prbrown
+//
+// Offset, hex and ASCII dump of the input, in the layout of hexdump -C.
+package main
+
+import "io"
+import "fmt"
+
+const HEXDUMP_COLS_DEFAULT int = 16
+
+// HexDump writes src to dst as rows of cols bytes, each row giving the
+// offset, the bytes in hex, split in two halves, and the bytes as ASCII with
+// non-printing ones shown as '.'. A last line gives the total length. Every
+// row is written; unlike hexdump -C, repeated rows are not folded into '*'.
+func HexDump(dst io.Writer, src io.Reader, cols int) error {
+   return filter_copy(new_hex_writer(dst, cols), src)
+}
+
+// hex_writer dumps what is written to it, keeping a partial row until the
+// row fills or Close
+type hex_writer struct {
+   dst io.Writer
+   cols int
+   offset int64
+   row []byte
+   out_buf []byte
+}
+
+func new_hex_writer(dst io.Writer, cols int) *hex_writer {
+   if cols < 1 {
+      cols = HEXDUMP_COLS_DEFAULT
+   }
+   return &hex_writer{dst: dst, cols: cols, row: make([]byte, 0, cols)}
+}
+
+func (h *hex_writer) Write(p []byte) (int, error) {
+   n := len(p)
+   for len(p) > 0 {
+      c := copy(h.row[len(h.row):h.cols], p)
+      h.row = h.row[:len(h.row)+c]
+      p = p[c:]
+
+      if len(h.row) == h.cols {
+         if ok := h.write_row(); ok != nil {
+            return 0, ok
+         }
+      }
+   }
+   return n, nil
+}
+
+// Close dumps the partial row and the final offset
+func (h *hex_writer) Close() error {
+   if len(h.row) > 0 {
+      if ok := h.write_row(); ok != nil {
+         return ok
+      }
+   }
+   if h.offset == 0 {
+      return nil
+   }
+   _, ok := fmt.Fprintf(h.dst, "%08x\n", h.offset)
+   return ok
+}
+
+func (h *hex_writer) write_row() error {
+   const hex_digits = "0123456789abcdef"
+   half := (h.cols+1) / 2
+
+   out := fmt.Appendf(h.out_buf[:0], "%08x  ", h.offset)
+   for i := 0; i < h.cols; i++ {
+      if i == half {
+         out = append(out, ' ')
+      }
+      if i < len(h.row) {
+         out = append(out, hex_digits[h.row[i]>>4], hex_digits[h.row[i]&0xF], ' ')
+      } else {
+         out = append(out, ' ', ' ', ' ')
+      }
+   }
+
+   out = append(out, ' ', '|')
+   for _, ch := range h.row {
+      if ch < ' ' || ch >= 0x7F {
+         ch = '.'
+      }
+      out = append(out, ch)
+   }
+   out = append(out, '|', '\n')
+   h.out_buf = out
+
+   h.offset += int64(len(h.row))
+   h.row = h.row[:0]
+   _, ok := h.dst.Write(out)
+   return ok
+}
@@ -0,0 +1,41 @@
+// Gotilities - cat
+// Author: prbrown
+//
+// --show-io-info, the block and buffer sizes picked for each file, how its
+// output is buffered and whether FIONREAD is asked, on stderr.
+package main
+
+import "os"
+import "fmt"
+import "syscall"
+
+// the capacities run() gives cat() for blocks of in_size read and
+// out_bSize written: room for the sentinel, and for a block escaped at the
+// widest with a line number, over what waits to be written
+func (st *cat_state) buffer_sizes(in_size int64, out_bSize int64) (int64, int64) {
+   return in_size+1, out_bSize-1+in_size*int64(st.max_escape())+LINE_COUNTER_BUF_LEN
+}
+
+// writes the sizes fName is read and written with, from its in_stat and
+// the output's out_stat, as with -n
+// "cat: FILE: st_blksize 4096 in, 4096 out; blocks of 131072 in, 131072 out; buffers of 131073 in, 131089 out; block buffered; FIONREAD on"
+func write_io_info(st *cat_state, fName string, in_stat *syscall.Stat_t, out_stat *syscall.Stat_t, in_size int64, out_bSize int64) {
+   fionread := "off"
+   if st.use_fionread {
+      fionread = "on"
+   }
+   // a plain copy passes each block straight through
+   buffers := fmt.Sprintf("a buffer of %d", in_size)
+   if st.transforms() {
+      in_buf, out_buf := st.buffer_sizes(in_size, out_bSize)
+      buffers = fmt.Sprintf("buffers of %d in, %d out", in_buf, out_buf)
+   }
+   buffering := "block buffered"
+   if st.unbuffered {
+      buffering = "unbuffered"
+   } else if st.line_buffered {
+      buffering = "line buffered"
+   }
+   fmt.Fprintf(os.Stderr, "cat: %s: st_blksize %d in, %d out; blocks of %d in, %d out; %s; %s; FIONREAD %s\n",
+      fName, in_stat.Blksize, out_stat.Blksize, in_size, out_bSize, buffers, buffering, fionread)
+}
@@ -0,0 +1,154 @@
+// Gotilities - join
+// Author: prbrown
+//
+// --join, the lines of two sorted files that share a field written as one.
+package main
+
+import "io"
+import "bufio"
+import "bytes"
+
+// the fields of line, split at each delim or, when delim is 0, at each run
+// of blanks as join does
+func join_fields(line []byte, delim byte) [][]byte {
+   if delim == 0 {
+      return bytes.Fields(line)
+   }
+   return bytes.Split(line, []byte{delim})
+}
+
+// a run of lines of one input with the same join field, as fields
+type join_group struct {
+   key []byte
+   lines [][][]byte
+}
+
+// join_reader reads an input a join_group at a time, holding the line that
+// starts the next group
+type join_reader struct {
+   ls *line_scanner
+   field int
+   delim byte
+   next [][]byte // fields of the line read ahead, nil at the end
+}
+
+// the join field of fields, empty when the line is short of it
+func (r *join_reader) key(fields [][]byte) []byte {
+   if r.field <= len(fields) {
+      return fields[r.field-1]
+   }
+   return nil
+}
+
+func (r *join_reader) read_line() error {
+   line, ok := r.ls.next()
+   if ok == io.EOF {
+      r.next = nil
+      return nil
+   } else if ok != nil {
+      return ok
+   }
+   // a copy, as the group holds it while more is read
+   line = bytes.Clone(bytes.TrimSuffix(line, []byte{'\n'}))
+   r.next = join_fields(line, r.delim)
+   if r.next == nil {
+      r.next = [][]byte{} // a blank line is still a line
+   }
+   return nil
+}
+
+// the next group, with no lines at the end of the input
+func (r *join_reader) group() (join_group, error) {
+   var g join_group
+   if r.next == nil {
+      return g, nil
+   }
+   g.key = r.key(r.next)
+   for r.next != nil && bytes.Equal(r.key(r.next), g.key) {
+      g.lines = append(g.lines, r.next)
+      if ok := r.read_line(); ok != nil {
+         return g, ok
+      }
+   }
+   return g, nil
+}
+
+// join writes, for each pair of a line of a and a line of b with the same
+// field number field, the field and then the other fields of each line. Both
+// inputs are sorted on that field. unpaired tells whether the lines of a, and
+// of b, without a pair are written as well, as join -a.
+func join(dst io.Writer, a io.Reader, b io.Reader, field int, delim byte, unpaired [2]bool, blk_size int64) error {
+   readers := [2]*join_reader{
+      {ls: new_line_scanner(a, blk_size), field: field, delim: delim},
+      {ls: new_line_scanner(b, blk_size), field: field, delim: delim},
+   }
+   var groups [2]join_group
+   for i, r := range readers {
+      var ok error
+      if ok = r.read_line(); ok == nil {
+         groups[i], ok = r.group()
+      }
+      if ok != nil {
+         return ok
+      }
+   }
+
+   out_sep := delim
+   if out_sep == 0 {
+      out_sep = ' '
+   }
+   out := bufio.NewWriterSize(dst, int(blk_size))
+   var out_buf []byte
+   write := func(key []byte, fields ...[][]byte) error {
+      out_buf = append(out_buf[:0], key...)
+      for _, line := range fields {
+         for i, f := range line {
+            if i != field-1 {
+               out_buf = append(append(out_buf, out_sep), f...)
+            }
+         }
+      }
+      _, ok := out.Write(append(out_buf, '\n'))
+      return ok
+   }
+
+   for len(groups[0].lines) > 0 || len(groups[1].lines) > 0 {
+      c := 0
+      if len(groups[1].lines) == 0 {
+         c = -1
+      } else if len(groups[0].lines) == 0 {
+         c = 1
+      } else {
+         c = bytes.Compare(groups[0].key, groups[1].key)
+      }
+
+      if c == 0 {
+         for _, la := range groups[0].lines {
+            for _, lb := range groups[1].lines {
+               if ok := write(groups[0].key, la, lb); ok != nil {
+                  return ok
+               }
+            }
+         }
+      }
+      for i := range groups {
+         side := 2*i - 1 // the c when this side's group has no pair
+         if c != 0 && c != side {
+            continue
+         }
+         if c == side && unpaired[i] {
+            for _, line := range groups[i].lines {
+               if ok := write(groups[i].key, line); ok != nil {
+                  return ok
+               }
+            }
+         }
+         var ok error
+         if groups[i], ok = readers[i].group(); ok != nil {
+            out.Flush()
+            return ok
+         }
+      }
+   }
+   return out.Flush()
+}
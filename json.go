@@ -0,0 +1,59 @@
+// Gotilities - cat
+// Author: prbrown
+//
+// --json, each output line as a JSON object of its own, for jq and the like.
+package main
+
+import "strconv"
+import "unicode/utf8"
+
+// appends the line, numbered num when not 0, as {"n":NUM,"line":"LINE"} and
+// a newline
+func append_json_line(dst []byte, line []byte, num int) []byte {
+   dst = append(dst, '{')
+   if num > 0 {
+      dst = append(dst, `"n":`...)
+      dst = strconv.AppendInt(dst, int64(num), 10)
+      dst = append(dst, ',')
+   }
+   dst = append(dst, `"line":`...)
+   dst = append_json_string(dst, line)
+   return append(dst, '}', '\n')
+}
+
+// appends s as a JSON string: quotes, backslashes and control characters
+// escaped, and bytes that are not UTF-8 made U+FFFD, as encoding/json does
+func append_json_string(dst []byte, s []byte) []byte {
+   const hex_digits = "0123456789abcdef"
+   dst = append(dst, '"')
+   for len(s) > 0 {
+      ch := s[0]
+      if ch >= utf8.RuneSelf {
+         r, size := utf8.DecodeRune(s)
+         if r == utf8.RuneError && size == 1 {
+            dst = append(dst, "\ufffd"...)
+         } else {
+            dst = append(dst, s[:size]...)
+         }
+         s = s[size:]
+         continue
+      }
+      s = s[1:]
+
+      switch {
+      case ch == '"' || ch == '\\':
+         dst = append(dst, '\\', ch)
+      case ch == '\n':
+         dst = append(dst, '\\', 'n')
+      case ch == '\r':
+         dst = append(dst, '\\', 'r')
+      case ch == '\t':
+         dst = append(dst, '\\', 't')
+      case ch < 0x20 || ch == 0x7F:
+         dst = append(dst, '\\', 'u', '0', '0', hex_digits[ch >> 4], hex_digits[ch & 0xF])
+      default:
+         dst = append(dst, ch)
+      }
+   }
+   return append(dst, '"')
+}
@@ -0,0 +1,65 @@
+// Gotilities - cat
+// Author: prbrown
+//
+// --max-line-length and --on-long-line, a bound on the input lines the line
+// at a time modes hold in full.
+package main
+
+import "io"
+import "fmt"
+
+// long_line_reader passes on src with no line longer than max bytes, its
+// separator left out: past that it fails, or with split starts a new line
+type long_line_reader struct {
+   src io.Reader
+   max int64
+   split bool
+   sep byte
+   n_line int64 // bytes of the line so far
+   rest []byte  // read on from where a line was split
+   ok error     // what the read of rest returned, for once it is given out
+}
+
+func new_long_line_reader(src io.Reader, max int64, split bool, sep byte) *long_line_reader {
+   return &long_line_reader{src: src, max: max, split: split, sep: sep}
+}
+
+func (r *long_line_reader) Read(p []byte) (int, error) {
+   var n int
+   var ok error
+   if len(r.rest) > 0 {
+      n = copy(p, r.rest)
+      if r.rest = r.rest[n:]; len(r.rest) == 0 {
+         ok, r.ok = r.ok, nil
+      }
+   } else if r.ok != nil {
+      ok, r.ok = r.ok, nil
+      return 0, ok
+   } else {
+      n, ok = r.src.Read(p)
+   }
+
+   for i := 0; i < n; i++ {
+      if p[i] == r.sep {
+         r.n_line = 0
+         continue
+      }
+      if r.n_line < r.max {
+         r.n_line++
+         continue
+      }
+      if !r.split {
+         return i, fmt.Errorf("line longer than %d bytes", r.max)
+      }
+
+      // the byte past max starts the next line, after a sep in its place
+      r.rest = append(append(make([]byte, 0, n-i+len(r.rest)), p[i:n]...), r.rest...)
+      if ok != nil {
+         r.ok = ok
+      }
+      p[i] = r.sep
+      r.n_line = 0
+      return i+1, nil
+   }
+   return n, ok
+}
@@ -0,0 +1,158 @@
+// Gotilities - grep, sed -n 'M,Np'
+// Author: prbrown
+//
+// Pass on only the input lines matching, or not matching, a regular
+// expression, or in a range of line numbers, with the cat options applied
+// to the lines kept.
+package main
+
+import "io"
+import "fmt"
+import "bytes"
+import "regexp"
+import "strings"
+import "strconv"
+
+// --match, and --invert-match, as a cat_state.keep_line
+func match_filter(re *regexp.Regexp, invert bool) func(line []byte) bool {
+   return func(line []byte) bool {
+      return re.Match(line) != invert
+   }
+}
+
+// --lines=START:END, either end left out for no limit; a lone N is N:N
+func parse_line_range(s string) (int64, int64, error) {
+   invalid := fmt.Errorf("invalid argument '%s' for '--lines'", s)
+   start_s, end_s, is_range := strings.Cut(s, ":")
+   if !is_range {
+      end_s = start_s
+   }
+
+   var start, end int64 = 1, 0
+   var ok error
+   if start_s != "" {
+      if start, ok = strconv.ParseInt(start_s, 10, 64); ok != nil || start < 1 {
+         return 0, 0, invalid
+      }
+   }
+   if end_s != "" {
+      if end, ok = strconv.ParseInt(end_s, 10, 64); ok != nil || end < start {
+         return 0, 0, invalid
+      }
+   }
+   return start, end, nil
+}
+
+// whether a line filter, or an option shaping lines such as --prefix, is
+// set, so that run() goes a line at a time
+func (st *cat_state) filters() bool {
+   return st.keep_line != nil || st.lines_from > 1 || st.lines_to > 0 || st.number_line != nil || st.collapse || len(st.prefix) > 0 || len(st.suffix) > 0 || st.json || st.select_line != nil
+}
+
+// whether --lines has passed its END, so no later line is written
+func (st *cat_state) lines_done() bool {
+   return st.lines_to > 0 && st.line_index >= st.lines_to
+}
+
+// appends the line number num, if not 0, and with starts the --prefix
+func (st *cat_state) append_line_start(dst []byte, num int, starts bool) []byte {
+   if starts && st.prefix_first {
+      dst = append(dst, st.prefix...)
+   }
+   if num > 0 {
+      dst = st.append_line_num(dst)
+   }
+   if starts && !st.prefix_first {
+      dst = append(dst, st.prefix...)
+   }
+   return dst
+}
+
+// appends a rendered line as it is written, its start and the line and its
+// separator, or with --json the object made of it
+func (st *cat_state) append_line(dst []byte, rendered []byte, num int, starts bool, has_nl bool) []byte {
+   if st.json {
+      return append_json_line(dst, rendered, num)
+   }
+   dst = st.append_line_start(dst, num, starts)
+   dst = append(dst, rendered...)
+   if has_nl {
+      dst = append(dst, st.sep)
+   }
+   return dst
+}
+
+// filter_lines is run() a line at a time, writing only the lines in the
+// --lines range that st.keep_line, if set, accepts. Numbering and -s see just
+// the lines kept, or with st.number_original every input line, as if the
+// rest were written too.
+func (st *cat_state) filter_lines(f io.Reader, in_size int64, out_bSize int64) error {
+   ls := new_line_scanner(f, in_size)
+   ls.sep = st.sep
+   var line_buf, out_buf []byte
+
+   for ;; {
+      line, ok := ls.next()
+      if ok == nil && st.lines_done() {
+         ok = io.EOF // the rest of the input is never written
+      }
+      if ok != nil {
+         var flush_ok error
+         out_buf, flush_ok = st.write_pending(out_buf)
+         if ok == io.EOF {
+            return flush_ok
+         }
+         return ok
+      }
+      st.stats.BytesRead += int64(len(line))
+
+      has_nl := line[len(line)-1] == st.sep
+      if has_nl {
+         line = line[:len(line)-1]
+      }
+
+      st.line_index++
+      keep := st.line_index >= st.lines_from && (st.keep_line == nil || st.keep_line(line))
+      if keep && st.select_line != nil {
+         st.select_buf, keep = st.select_line(st.select_buf[:0], line)
+         line = st.select_buf
+      }
+      if !keep && !st.number_original {
+         continue
+      }
+
+      if !keep && st.number_line == nil {
+         // only whether the line is blank counts, spare rendering it all
+         if st.opts.TrimTrailing || st.collapse {
+            line = bytes.TrimRight(line, " \t")
+         }
+         if st.collapse {
+            line = bytes.TrimLeft(line, " \t")
+         }
+         line = line[:min(len(line), 1)]
+      }
+
+      starts := st.new_lines >= 0 // not the rest of a line the last file left open
+      rendered, num, shown := st.render_line(line_buf[:0], line, has_nl)
+      line_buf = rendered
+      if !keep || !shown {
+         continue
+      }
+
+      // (--squeeze-all) the blank line waits, maybe into the next file
+      if st.opts.SqueezeAll && st.new_lines > 0 {
+         st.held_line = st.append_line(st.held_line[:0], rendered, num, starts, true)
+         continue
+      }
+      out_buf = append(out_buf, st.held_line...)
+      st.held_line = st.held_line[:0]
+
+      out_buf = st.append_line(out_buf, rendered, num, starts, has_nl)
+
+      if int64(len(out_buf)) >= out_bSize || st.line_buffered && has_nl || st.unbuffered {
+         if out_buf, ok = st.write_pending(out_buf); ok != nil {
+            return ok
+         }
+      }
+   }
+}
@@ -0,0 +1,111 @@
+// Gotilities - sort -m
+// Author: prbrown
+//
+// --merge, files whose lines are sorted already written as one sorted run,
+// a line of each held at a time.
+package main
+
+import "io"
+import "os"
+import "bufio"
+import "bytes"
+import "strconv"
+
+// compares lines by the number each starts with, as sort -n, a line without
+// one counting as 0, and lines of the same number byte by byte
+func compare_numeric(a []byte, b []byte) int {
+   na, nb := leading_number(a), leading_number(b)
+   if na < nb {
+      return -1
+   } else if na > nb {
+      return 1
+   }
+   return bytes.Compare(a, b)
+}
+
+// the number line starts with, after any blanks
+func leading_number(line []byte) float64 {
+   line = bytes.TrimLeft(line, " \t")
+   end := 0
+   if end < len(line) && line[end] == '-' {
+      end++
+   }
+   point := false
+   for end < len(line) && ('0' <= line[end] && line[end] <= '9' || line[end] == '.' && !point) {
+      point = point || line[end] == '.'
+      end++
+   }
+   n, ok := strconv.ParseFloat(string(line[:end]), 64)
+   if ok != nil {
+      return 0
+   }
+   return n
+}
+
+// merge writes the lines of srcs, each in the order of compare already, to
+// dst in that order, each ended by a newline; of equal lines the one from
+// the earlier src goes first, as sort -m
+func merge(dst io.Writer, srcs []io.Reader, compare func(a []byte, b []byte) int, blk_size int64) error {
+   scanners := make([]*line_scanner, len(srcs))
+   heads := make([][]byte, len(srcs)) // the next line of each, nil once it is done
+   next := func(i int) error {
+      line, ok := scanners[i].next()
+      if ok == io.EOF {
+         heads[i] = nil
+         return nil
+      } else if ok != nil {
+         return ok
+      }
+      heads[i] = bytes.TrimSuffix(line, []byte{'\n'})
+      return nil
+   }
+   for i, src := range srcs {
+      scanners[i] = new_line_scanner(src, blk_size)
+      if ok := next(i); ok != nil {
+         return ok
+      }
+   }
+
+   out := bufio.NewWriterSize(dst, int(blk_size))
+   for ;; {
+      first := -1
+      for i, head := range heads {
+         if head != nil && (first < 0 || compare(head, heads[first]) < 0) {
+            first = i
+         }
+      }
+      if first < 0 {
+         return out.Flush()
+      }
+
+      out.Write(heads[first])
+      if ok := out.WriteByte('\n'); ok != nil {
+         return ok
+      }
+      if ok := next(first); ok != nil {
+         out.Flush()
+         return ok
+      }
+   }
+}
+
+// opens the files of cfg for --merge and --join, warning of any that do not
+// open; the files are to be closed by the caller
+func open_files(cfg *Config) ([]*os.File, bool) {
+   var files []*os.File
+   ret := true
+   for i, name := range cfg.Files {
+      if fd, is_fd := cfg.fds[i]; is_fd {
+         files = append(files, os.NewFile(uintptr(fd), name))
+         continue
+      }
+      f, _, ok := probe(name)
+      if ok != nil {
+         print_error(cfg, ok)
+         ret = false
+         continue
+      }
+      files = append(files, f)
+   }
+   return files, ret
+}
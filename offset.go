@@ -0,0 +1,43 @@
+// Gotilities - cat
+// Author: prbrown
+//
+// --start-offset, input taken from a given byte on.
+package main
+
+import "os"
+import "io"
+
+// skip_input moves src on n bytes, by seeking where it can and otherwise
+// reading them away. Input shorter than n is left at its end.
+func skip_input(src io.Reader, n int64) error {
+   if n <= 0 {
+      return nil
+   }
+
+   // pipes and terminals don't seek, or seek without moving
+   seekable := true
+   if f, is_file := src.(*os.File); is_file {
+      info, ok := f.Stat()
+      seekable = ok == nil && info.Mode().IsRegular()
+   }
+   if s, is_seeker := src.(io.Seeker); is_seeker && seekable {
+      // no further than the end, which a seek would go past
+      cur, ok := s.Seek(0, io.SeekCurrent)
+      var end int64
+      if ok == nil {
+         end, ok = s.Seek(0, io.SeekEnd)
+      }
+      if ok == nil {
+         _, ok = s.Seek(min(cur+n, end), io.SeekStart)
+      }
+      if ok == nil {
+         return nil
+      }
+   }
+
+   _, ok := io.CopyN(io.Discard, src, n)
+   if ok == io.EOF {
+      return nil
+   }
+   return ok
+}
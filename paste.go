@@ -0,0 +1,66 @@
+// Gotilities - paste
+// Author: prbrown
+//
+// Merge the lines of two inputs side by side.
+package main
+
+import "io"
+import "bufio"
+
+// Paste writes to dst each line of a joined by delim to the line of b at the
+// same place, as paste does with two files; a delim of 0 is a tab. When one
+// input runs out first, its side of the remaining lines is left empty. Every
+// line written ends in a newline.
+func Paste(dst io.Writer, a io.Reader, b io.Reader, delim byte) error {
+   return paste(dst, a, b, delim, IO_BLK_SIZE_DEFAULT)
+}
+
+func paste(dst io.Writer, a io.Reader, b io.Reader, delim byte, blk_size int64) error {
+   if delim == 0 {
+      delim = '\t'
+   }
+   ls_a, ls_b := new_line_scanner(a, blk_size), new_line_scanner(b, blk_size)
+   out := bufio.NewWriterSize(dst, int(blk_size))
+   var out_buf []byte
+   a_done, b_done := false, false
+
+   for ;; {
+      out_buf = out_buf[:0]
+      var ok error
+      if out_buf, a_done, ok = paste_side(out_buf, ls_a, a_done); ok != nil {
+         out.Flush()
+         return ok
+      }
+      out_buf = append(out_buf, delim)
+      if out_buf, b_done, ok = paste_side(out_buf, ls_b, b_done); ok != nil {
+         out.Flush()
+         return ok
+      }
+      if a_done && b_done {
+         return out.Flush()
+      }
+
+      if _, ok = out.Write(append(out_buf, '\n')); ok != nil {
+         return ok
+      }
+   }
+}
+
+// appends the next line of ls, without its newline, to out_buf; done tells
+// whether ls has run out, before this call or in it
+func paste_side(out_buf []byte, ls *line_scanner, done bool) ([]byte, bool, error) {
+   if done {
+      return out_buf, true, nil
+   }
+   line, ok := ls.next()
+   if ok == io.EOF {
+      return out_buf, true, nil
+   } else if ok != nil {
+      return out_buf, true, ok
+   }
+
+   if line[len(line)-1] == '\n' {
+      line = line[:len(line)-1]
+   }
+   return append(out_buf, line...), false, nil
+}
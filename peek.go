@@ -0,0 +1,50 @@
+// Gotilities - cat
+// Author: prbrown
+//
+// peek_reader, the first bytes of an input looked at without reading them
+// away, for the options that tell what a file is by how it starts.
+package main
+
+import "io"
+
+// peek_reader gives back the bytes peeked at before any more of src, then
+// reads src straight through
+type peek_reader struct {
+   src io.Reader
+   buf []byte
+}
+
+// a peek_reader of src that peeks up to blk_size bytes with one read
+func new_peek_reader(src io.Reader, blk_size int64) *peek_reader {
+   return &peek_reader{src: src, buf: make([]byte, 0, blk_size)}
+}
+
+// peek returns the bytes to come, at least n of them unless the input ends
+// or fails first, and as many more as the reads for them brought
+func (r *peek_reader) peek(n int) ([]byte, error) {
+   for len(r.buf) < n {
+      if len(r.buf) == cap(r.buf) {
+         r.buf = append(make([]byte, 0, max(n, 2*cap(r.buf))), r.buf...)
+      }
+      n_read, ok := retry_read(r.src, r.buf[len(r.buf):cap(r.buf)])
+      r.buf = r.buf[:len(r.buf)+n_read]
+      if ok != nil {
+         return r.buf, ok
+      }
+   }
+   return r.buf, nil
+}
+
+// discard drops the first n bytes peeked
+func (r *peek_reader) discard(n int) {
+   r.buf = r.buf[n:]
+}
+
+func (r *peek_reader) Read(p []byte) (int, error) {
+   if len(r.buf) == 0 {
+      return r.src.Read(p)
+   }
+   n := copy(p, r.buf)
+   r.buf = r.buf[n:]
+   return n, nil
+}
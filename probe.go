@@ -0,0 +1,61 @@
+// Gotilities - cat
+// Author: prbrown
+//
+// --list-files, the FILE arguments checked without reading any of them.
+package main
+
+import "os"
+import "fmt"
+import "errors"
+import "syscall"
+
+// list_files writes a line on each file of cfg.Files, saying whether cat
+// could read it, and says whether all could be
+func list_files(cfg *Config, st *cat_state) bool {
+   ret := true
+   for i, name := range cfg.Files {
+      status, readable := probe_status(cfg, i, name)
+      ret = ret && readable
+      if _, ok := st.write([]byte(name + ": " + status + "\n")); ok != nil {
+         print_error(cfg, ok)
+         return false
+      }
+   }
+   return ret
+}
+
+// what probe finds of the i'th FILE, and whether it can be read
+func probe_status(cfg *Config, i int, name string) (string, bool) {
+   if is_url(name) {
+      return "url, not fetched", true
+   }
+
+   var in_stat syscall.Stat_t
+   var ok error
+   if fd, is_fd := cfg.fds[i]; is_fd {
+      in_stat, ok = fstat(os.NewFile(uintptr(fd), name))
+   } else {
+      var fDes *os.File
+      if fDes, in_stat, ok = probe(name); ok == nil && fDes != os.Stdin {
+         fDes.Close()
+      }
+   }
+
+   switch {
+   case errors.Is(ok, os.ErrNotExist):
+      return "missing", false
+   case errors.Is(ok, os.ErrPermission):
+      return "permission denied", false
+   case ok != nil:
+      var errno syscall.Errno
+      if errors.As(ok, &errno) {
+         return errno.Error(), false
+      }
+      return ok.Error(), false
+   case in_stat.Mode & syscall.S_IFMT == syscall.S_IFDIR:
+      return "directory", false
+   case in_stat.Mode & syscall.S_IFMT == syscall.S_IFREG:
+      return fmt.Sprintf("readable, %d bytes", in_stat.Size), true
+   }
+   return "readable", true
+}
@@ -0,0 +1,116 @@
+// Gotilities - cat
+// Author: prbrown
+//
+// --progress, a running count of the bytes read from each file on stderr,
+// and --pv, the same as a bar redrawn in place.
+package main
+
+import "io"
+import "fmt"
+import "time"
+import "strings"
+
+const PROGRESS_INTERVAL = 500 * time.Millisecond
+
+// progress_reader reports, at most once an interval and once more at the
+// end, how much of src has been read. size is the length of a regular file,
+// 0 when unknown.
+type progress_reader struct {
+   src io.Reader
+   status io.Writer
+   name string
+   size int64
+   n_read int64
+   last time.Time
+   done bool
+
+   // (--pv) a bar in place of the count lines, the rate taken from start
+   bar bool
+   start time.Time
+}
+
+func new_progress_reader(src io.Reader, status io.Writer, name string, size int64) *progress_reader {
+   now := time.Now()
+   return &progress_reader{src: src, status: status, name: name, size: size, last: now, start: now}
+}
+
+// a progress_reader drawing a bar, for a terminal
+func new_pv_reader(src io.Reader, status io.Writer, name string, size int64) *progress_reader {
+   p := new_progress_reader(src, status, name, size)
+   p.bar = true
+   return p
+}
+
+func (p *progress_reader) Read(b []byte) (int, error) {
+   n_read, ok := p.src.Read(b)
+   p.n_read += int64(n_read)
+
+   // the last word on the file, however soon it ends; the bar is left on
+   // its line
+   if ok == io.EOF {
+      if !p.done {
+         p.done = true
+         p.report()
+         if p.bar {
+            fmt.Fprintln(p.status)
+         }
+      }
+      return n_read, ok
+   }
+
+   if now := time.Now(); now.Sub(p.last) >= PROGRESS_INTERVAL {
+      p.last = now
+      p.report()
+   }
+   return n_read, ok
+}
+
+func (p *progress_reader) report() {
+   if p.bar {
+      p.draw_bar()
+      return
+   }
+   if p.size > 0 {
+      fmt.Fprintf(p.status, "cat: %s: %d bytes (%d%%)\n", p.name, p.n_read, p.n_read * 100 / p.size)
+   } else {
+      fmt.Fprintf(p.status, "cat: %s: %d bytes\n", p.name, p.n_read)
+   }
+}
+
+const PV_BAR_WIDTH = 20
+
+// redraws the bar over the last: the bytes read, how fast, and with a known
+// size how far along and how long to go
+func (p *progress_reader) draw_bar() {
+   elapsed := time.Since(p.start).Seconds()
+   rate := float64(p.n_read)
+   if elapsed > 0 {
+      rate /= elapsed
+   }
+
+   line := fmt.Sprintf("\rcat: %s: %s %s/s", p.name, iec_size(float64(p.n_read)), iec_size(rate))
+   if p.size > 0 {
+      done := min(p.n_read, p.size)
+      filled := int(done * PV_BAR_WIDTH / p.size)
+      eta := "?"
+      if rate > 0 {
+         eta = time.Duration(float64(p.size - done) / rate * float64(time.Second)).Round(time.Second).String()
+      }
+      line += fmt.Sprintf(" [%s%s] %3d%% ETA %s", strings.Repeat("=", filled), strings.Repeat(" ", PV_BAR_WIDTH - filled), done * 100 / p.size, eta)
+   }
+   fmt.Fprint(p.status, line + "\033[K") // and clear what is left of a longer line
+}
+
+// n bytes in B, KiB, MiB or GiB, to one place
+func iec_size(n float64) string {
+   units := []string{"B", "KiB", "MiB", "GiB"}
+   i := 0
+   for n >= 1024 && i < len(units)-1 {
+      n /= 1024
+      i++
+   }
+   if i == 0 {
+      return fmt.Sprintf("%.0f%s", n, units[i])
+   }
+   return fmt.Sprintf("%.1f%s", n, units[i])
+}
@@ -0,0 +1,89 @@
+// Gotilities - cat
+// Author: prbrown
+//
+// Reader, the pull form of Cat.
+package main
+
+import "io"
+
+// Reader yields the cat transformation of another reader, so it can be
+// handed to io.Copy and the like. Input is transformed a chunk at a time;
+// output of a chunk that does not fit the caller's buffer is kept for the
+// following Read calls.
+type Reader struct {
+   st *cat_state
+   src io.Reader
+   in_buf []byte
+   out_buf []byte
+   out_pos int  // start of the bytes not yet returned
+   skip int64   // Options.StartOffset, skipped on the first Read
+   ok error     // read error held back until out_buf drains
+}
+
+// NewReader returns a Reader applying opts to src.
+func NewReader(src io.Reader, opts Options) *Reader {
+   r := &Reader{
+      st: new_cat_state(nil, opts),
+      src: src,
+      skip: opts.StartOffset,
+      in_buf: make([]byte, 0, IO_BLK_SIZE_DEFAULT+1),
+   }
+   if r.skip == 0 {
+      r.src = new_cr_reader(new_tr_reader(src, r.st), opts)
+   }
+   return r
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+   if r.skip > 0 {
+      ok := skip_input(r.src, r.skip)
+      r.skip = 0
+      if ok != nil {
+         return 0, ok
+      }
+      r.src = new_cr_reader(new_tr_reader(r.src, r.st), r.st.opts)
+   }
+
+   if r.st.opts.MaxBytes > 0 {
+      room := r.st.opts.MaxBytes - r.st.stats.BytesWritten
+      if room == 0 {
+         return 0, io.EOF
+      } else if int64(len(p)) > room {
+         p = p[:room]
+      }
+   }
+
+   if !r.st.transforms() {
+      n_read, ok := r.src.Read(p)
+      r.st.stats.BytesRead += int64(n_read)
+      r.st.stats.BytesWritten += int64(n_read)
+      return n_read, ok
+   }
+
+   for r.out_pos == len(r.out_buf) {
+      if r.ok != nil {
+         return 0, r.ok
+      }
+
+      r.in_buf = r.in_buf[:cap(r.in_buf)]
+      n_read, ok := r.src.Read(r.in_buf[:len(r.in_buf)-1]) // leave room for sentinel
+      r.st.stats.BytesRead += int64(n_read)
+      r.ok = ok
+
+      r.out_pos = 0
+      r.out_buf = r.out_buf[:0]
+      if n_read > 0 {
+         r.in_buf[n_read] = r.st.sep // sentinel, set in place rather than appended
+         r.out_buf = r.st.transform(r.in_buf[:n_read+1], r.out_buf)
+         if r.st.opts.LineEnding == "crlf" {
+            r.st.crlf_buf = to_crlf(r.st.crlf_buf[:0], r.out_buf)
+            r.out_buf, r.st.crlf_buf = r.st.crlf_buf, r.out_buf
+         }
+      }
+   }
+
+   n := copy(p, r.out_buf[r.out_pos:])
+   r.out_pos += n
+   r.st.stats.BytesWritten += int64(n)
+   return n, nil
+}
@@ -0,0 +1,58 @@
+// Gotilities - cat
+// Author: prbrown
+//
+// --replay, the input given out a line each interval, as if a live stream.
+package main
+
+import "io"
+import "time"
+import "context"
+
+// replay_reader reads src a line at a time, the first at once and each
+// after it on the next tick, until ctx is done. The end of the input is
+// given as soon as it is found, not a tick later.
+type replay_reader struct {
+   ctx context.Context
+   ls *line_scanner
+   tick <-chan time.Time
+   stop func()
+   started bool
+   line []byte // what is left of the line given out
+}
+
+func new_replay_reader(ctx context.Context, src io.Reader, interval time.Duration, sep byte, blk_size int64) *replay_reader {
+   ticker := time.NewTicker(interval)
+   return replay_on_ticks(ctx, src, ticker.C, ticker.Stop, sep, blk_size)
+}
+
+// a replay_reader paced by tick rather than a ticker of its own, stop
+// called once it is done with it
+func replay_on_ticks(ctx context.Context, src io.Reader, tick <-chan time.Time, stop func(), sep byte, blk_size int64) *replay_reader {
+   ls := new_line_scanner(src, blk_size)
+   ls.sep = sep
+   return &replay_reader{ctx: ctx, ls: ls, tick: tick, stop: stop}
+}
+
+func (r *replay_reader) Read(p []byte) (int, error) {
+   if len(r.line) == 0 {
+      line, ok := r.ls.next()
+      if ok != nil {
+         r.stop()
+         return 0, ok
+      }
+
+      if r.started {
+         select {
+            case <-r.ctx.Done():
+               r.stop()
+               return 0, r.ctx.Err()
+            case <-r.tick:
+         }
+      }
+      r.started = true
+      r.line = line
+   }
+   n := copy(p, r.line)
+   r.line = r.line[n:]
+   return n, nil
+}
@@ -0,0 +1,95 @@
+// Gotilities - cat
+// Author: prbrown
+//
+// --replay paced by a fake clock: a line on each tick, and the end of the
+// input straight after the last.
+package main
+
+import "io"
+import "time"
+import "errors"
+import "context"
+import "strings"
+import "testing"
+
+// the result of one Read of a replay_reader, run apart so that the test
+// can see whether it waits
+type replay_read struct {
+   line string
+   ok error
+}
+
+func read_replay(r *replay_reader) <-chan replay_read {
+   done := make(chan replay_read, 1)
+   go func() {
+      buf := make([]byte, 64)
+      n, ok := r.Read(buf)
+      done <- replay_read{string(buf[:n]), ok}
+   }()
+   return done
+}
+
+// how long a Read may take that does not wait on a tick
+const REPLAY_TEST_WAIT = time.Second
+
+func TestReplayFakeClock(t *testing.T) {
+   tick := make(chan time.Time)
+   stopped := false
+   r := replay_on_ticks(context.Background(), strings.NewReader("a\nb\nc\n"), tick, func() { stopped = true }, '\n', 16)
+
+   // the first line at once
+   select {
+      case got := <-read_replay(r):
+         if got.line != "a\n" || got.ok != nil {
+            t.Fatalf("first read gave %q, %v", got.line, got.ok)
+         }
+      case <-time.After(REPLAY_TEST_WAIT):
+         t.Fatal("the first line waited for a tick")
+   }
+
+   // each other line once its tick comes, and not before
+   for _, want := range []string{"b\n", "c\n"} {
+      done := read_replay(r)
+      select {
+         case got := <-done:
+            t.Fatalf("%q given before its tick", got.line)
+         case <-time.After(10 * time.Millisecond):
+      }
+      tick <- time.Time{}
+      if got := <-done; got.line != want || got.ok != nil {
+         t.Fatalf("read gave %q, %v, want %q", got.line, got.ok, want)
+      }
+   }
+
+   // the end of the input with no tick more
+   select {
+      case got := <-read_replay(r):
+         if got.ok != io.EOF {
+            t.Fatalf("read after the last line gave %q, %v", got.line, got.ok)
+         }
+      case <-time.After(REPLAY_TEST_WAIT):
+         t.Fatal("the end of the input waited for a tick")
+   }
+   if !stopped {
+      t.Error("the ticker was not stopped")
+   }
+}
+
+func TestReplayCancel(t *testing.T) {
+   ctx, cancel := context.WithCancel(context.Background())
+   r := replay_on_ticks(ctx, strings.NewReader("a\nb\n"), make(chan time.Time), func() {}, '\n', 16)
+   if got := <-read_replay(r); got.line != "a\n" {
+      t.Fatalf("first read gave %q, %v", got.line, got.ok)
+   }
+
+   done := read_replay(r)
+   cancel()
+   select {
+      case got := <-done:
+         if !errors.Is(got.ok, context.Canceled) {
+            t.Fatalf("read after cancel gave %q, %v", got.line, got.ok)
+         }
+      case <-time.After(REPLAY_TEST_WAIT):
+         t.Fatal("the wait for a tick outlasted the context")
+   }
+}
@@ -0,0 +1,64 @@
+// Gotilities - cat
+// Author: prbrown
+//
+// Reads and writes that ride out interrupted system calls and descriptors
+// that are momentarily not ready.
+package main
+
+import "io"
+import "time"
+import "errors"
+import "syscall"
+
+// the wait after an EAGAIN doubles, from RETRY_WAIT_MIN up to RETRY_WAIT_MAX
+const RETRY_WAIT_MIN = time.Millisecond
+const RETRY_WAIT_MAX = 100 * time.Millisecond
+
+// whether the call that failed with ok is worth repeating
+func transient(ok error) bool {
+   return errors.Is(ok, syscall.EINTR) || errors.Is(ok, syscall.EAGAIN)
+}
+
+// the pause before repeating a call that failed with ok, and the pause for
+// the one after
+func retry_pause(ok error, wait time.Duration) time.Duration {
+   if !errors.Is(ok, syscall.EAGAIN) {
+      return wait // EINTR, straight back in
+   }
+   time.Sleep(wait)
+   if wait *= 2; wait > RETRY_WAIT_MAX {
+      wait = RETRY_WAIT_MAX
+   }
+   return wait
+}
+
+// retry_read is r.Read(b) repeated on EINTR and, after a pause, EAGAIN until
+// it gets data or another error
+func retry_read(r io.Reader, b []byte) (int, error) {
+   wait := RETRY_WAIT_MIN
+   for ;; {
+      n_read, ok := r.Read(b)
+      if ok == nil || !transient(ok) {
+         return n_read, ok
+      }
+      if n_read > 0 {
+         return n_read, nil // the data is good, the next read tries again
+      }
+      wait = retry_pause(ok, wait)
+   }
+}
+
+// retry_write is w.Write(b) carrying on with the rest of b after EINTR and,
+// after a pause, EAGAIN
+func retry_write(w io.Writer, b []byte) (int, error) {
+   wait := RETRY_WAIT_MIN
+   n_written := 0
+   for ;; {
+      n, ok := w.Write(b[n_written:])
+      n_written += n
+      if ok == nil || !transient(ok) {
+         return n_written, ok
+      }
+      wait = retry_pause(ok, wait)
+   }
+}
@@ -0,0 +1,70 @@
+// Gotilities - rev
+// Author: prbrown
+//
+// Reverse the characters of every line.
+package main
+
+import "io"
+import "bufio"
+import "unicode/utf8"
+
+// Rev writes each line of src to dst with its bytes reversed, the newline
+// staying at the end. A final line without a newline is reversed and left
+// without one.
+func Rev(dst io.Writer, src io.Reader) error {
+   return rev(dst, src, '\n', IO_BLK_SIZE_DEFAULT, false)
+}
+
+// RevUTF8 is Rev reversing runes instead of bytes, so multibyte characters
+// are kept whole. Bytes that are not valid UTF-8 are reversed one by one.
+func RevUTF8(dst io.Writer, src io.Reader) error {
+   return rev(dst, src, '\n', IO_BLK_SIZE_DEFAULT, true)
+}
+
+// rev is Rev or RevUTF8 of the lines ending with sep
+func rev(dst io.Writer, src io.Reader, sep byte, blk_size int64, runes bool) error {
+   ls := new_line_scanner(src, blk_size)
+   ls.sep = sep
+   out := bufio.NewWriterSize(dst, int(blk_size))
+   var out_buf []byte
+
+   for ;; {
+      line, ok := ls.next()
+      if ok == io.EOF {
+         return out.Flush()
+      } else if ok != nil {
+         out.Flush()
+         return ok
+      }
+
+      has_nl := line[len(line)-1] == sep
+      if has_nl {
+         line = line[:len(line)-1]
+      }
+
+      out_buf = out_buf[:0]
+      if runes {
+         for len(line) > 0 {
+            _, size := utf8.DecodeLastRune(line)
+            out_buf = append(out_buf, line[len(line)-size:]...)
+            line = line[:len(line)-size]
+         }
+      } else {
+         for i := len(line)-1; i >= 0; i-- {
+            out_buf = append(out_buf, line[i])
+         }
+      }
+      if has_nl {
+         out_buf = append(out_buf, sep)
+      }
+
+      if _, ok := out.Write(out_buf); ok != nil {
+         return ok
+      }
+   }
+}
+
+// --rev, characters as GNU rev does in a UTF-8 locale
+func rev_mode(dst io.Writer, src io.Reader, sep byte, blk_size int64) error {
+   return rev(dst, src, sep, blk_size, true)
+}
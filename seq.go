@@ -0,0 +1,140 @@
+// Gotilities - seq
+// Author: prbrown
+//
+// --seq, a run of numbers written in place of any input, as GNU seq.
+package main
+
+import "io"
+import "bufio"
+import "errors"
+import "strings"
+import "strconv"
+
+// a --seq FIRST:STEP:LAST, each number scaled by 10^prec, prec being the
+// most digits after the point that any of them is given with, so that
+// fractions step exactly
+type seq_spec struct {
+   first int64
+   step int64
+   last int64
+   prec int
+}
+
+var err_invalid_seq = errors.New("invalid sequence")
+
+// parses LAST, FIRST:LAST or FIRST:STEP:LAST, FIRST and STEP being 1 when
+// left out
+func parse_seq(s string) (seq_spec, error) {
+   parts := strings.Split(s, ":")
+   switch len(parts) {
+   case 1:
+      parts = []string{"1", "1", parts[0]}
+   case 2:
+      parts = []string{parts[0], "1", parts[1]}
+   case 3:
+   default:
+      return seq_spec{}, err_invalid_seq
+   }
+
+   var sp seq_spec
+   for _, part := range parts {
+      if _, frac, is_frac := strings.Cut(part, "."); is_frac {
+         sp.prec = max(sp.prec, len(frac))
+      }
+   }
+   var n [3]int64
+   for i, part := range parts {
+      var ok error
+      if n[i], ok = parse_scaled(part, sp.prec); ok != nil {
+         return seq_spec{}, ok
+      }
+   }
+   sp.first, sp.step, sp.last = n[0], n[1], n[2]
+   if sp.step == 0 {
+      return seq_spec{}, errors.New("the step must not be 0")
+   }
+   return sp, nil
+}
+
+// the decimal number s times 10^prec, s having at most prec digits after
+// its point
+func parse_scaled(s string, prec int) (int64, error) {
+   whole, frac, _ := strings.Cut(s, ".")
+   if whole == "" || whole == "-" || whole == "+" {
+      whole += "0" // as .5
+   }
+   if strings.ContainsAny(frac, "+-") {
+      return 0, err_invalid_seq
+   }
+   n, ok := strconv.ParseInt(whole + frac + strings.Repeat("0", prec-len(frac)), 10, 64)
+   if ok != nil {
+      return 0, err_invalid_seq
+   }
+   return n, nil
+}
+
+// appends the scaled n with its point put back
+func append_scaled(dst []byte, n int64, prec int) []byte {
+   abs := uint64(n)
+   if n < 0 {
+      dst = append(dst, '-')
+      abs = -abs
+   }
+   digits := strconv.AppendUint(nil, abs, 10)
+   if prec == 0 {
+      return append(dst, digits...)
+   }
+   for len(digits) <= prec {
+      digits = append([]byte{'0'}, digits...)
+   }
+   dst = append(dst, digits[:len(digits)-prec]...)
+   return append(append(dst, '.'), digits[len(digits)-prec:]...)
+}
+
+// write_seq writes the numbers of sp to dst, each ended by sep and laid out
+// as format, "ln", "rn" or "rz" as for --number-format, in the width of the
+// wider of FIRST and LAST; "" to write them as they are
+func write_seq(dst io.Writer, sp seq_spec, format string, sep byte, blk_size int64) error {
+   out := bufio.NewWriterSize(dst, int(blk_size))
+   width := 0
+   if format != "" {
+      width = max(len(append_scaled(nil, sp.first, sp.prec)), len(append_scaled(nil, sp.last, sp.prec)))
+   }
+
+   var num_buf, pad_buf []byte
+   for n := sp.first; sp.step > 0 && n <= sp.last || sp.step < 0 && n >= sp.last; n += sp.step {
+      num_buf = append_scaled(num_buf[:0], n, sp.prec)
+      pad_buf = pad_buf[:0]
+      for i := len(num_buf); i < width; i++ {
+         pad_buf = append(pad_buf, ' ')
+      }
+
+      switch format {
+      case "ln":
+         out.Write(num_buf)
+         out.Write(pad_buf)
+      case "rz":
+         if num_buf[0] == '-' {
+            out.WriteByte('-')
+            num_buf = num_buf[1:]
+         }
+         for range pad_buf {
+            out.WriteByte('0')
+         }
+         out.Write(num_buf)
+      default:
+         out.Write(pad_buf)
+         out.Write(num_buf)
+      }
+      if ok := out.WriteByte(sep); ok != nil {
+         return ok
+      }
+
+      // the next number is past LAST; stopping short of adding the step
+      // keeps it from overflowing
+      if sp.step > 0 && n > sp.last - sp.step || sp.step < 0 && n < sp.last - sp.step {
+         break
+      }
+   }
+   return out.Flush()
+}
@@ -0,0 +1,50 @@
+// Gotilities - shuf
+// Author: prbrown
+//
+// --shuffle, the lines of each file written in a random order, as shuf.
+package main
+
+import "io"
+import "bufio"
+import "math/rand"
+
+// Shuffle writes the lines of src to dst in an order picked by rng, each
+// ended by a newline. All of src is held in memory, so the memory used grows
+// with the input.
+func Shuffle(dst io.Writer, src io.Reader, rng *rand.Rand) error {
+   return shuffle(dst, src, rng, IO_BLK_SIZE_DEFAULT)
+}
+
+func shuffle(dst io.Writer, src io.Reader, rng *rand.Rand, blk_size int64) error {
+   // every line back to back in one buffer, and where each one ends
+   var data []byte
+   var ends []int
+
+   ls := new_line_scanner(src, blk_size)
+   for ;; {
+      line, ok := ls.next()
+      if ok == io.EOF {
+         break
+      } else if ok != nil {
+         return ok
+      }
+      data = append(data, line...)
+      if line[len(line)-1] != '\n' {
+         data = append(data, '\n')
+      }
+      ends = append(ends, len(data))
+   }
+
+   order := rng.Perm(len(ends))
+   out := bufio.NewWriterSize(dst, int(blk_size))
+   for _, i := range order {
+      start := 0
+      if i > 0 {
+         start = ends[i-1]
+      }
+      if _, ok := out.Write(data[start:ends[i]]); ok != nil {
+         return ok
+      }
+   }
+   return out.Flush()
+}
@@ -0,0 +1,66 @@
+// Gotilities - cat
+// Author: prbrown
+//
+// SIGINT and SIGTERM as a clean stop: the output so far is written and the
+// files closed before cat exits.
+package main
+
+import "os"
+import "io"
+import "time"
+import "context"
+import "syscall"
+import "os/signal"
+
+// the exit status after a signal, 128 + SIGINT as for a shell
+const EXIT_INTERRUPTED = 130
+
+// how long a stop may take before cat exits regardless
+const SIGNAL_GRACE = time.Second
+
+// stop_on_signal returns a context cancelled on SIGINT or SIGTERM. A cat
+// still running SIGNAL_GRACE later, e.g. blocked reading a terminal, or
+// signalled again, exits there and then.
+func stop_on_signal() context.Context {
+   sigs := make(chan os.Signal, 2)
+   signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+   ctx, cancel := context.WithCancel(context.Background())
+
+   go func() {
+      <-sigs
+      cancel()
+      select {
+      case <-sigs:
+      case <-time.After(SIGNAL_GRACE):
+      }
+      os.Exit(EXIT_INTERRUPTED)
+   }()
+   return ctx
+}
+
+// ctx_file is the ctx_reader of a file, keeping its descriptor for FIONREAD
+// and its seeking for --tail and --tac
+type ctx_file struct {
+   ctx_reader
+   f *os.File
+}
+
+func (c ctx_file) Fd() uintptr {
+   return c.f.Fd()
+}
+
+func (c ctx_file) Name() string {
+   return c.f.Name()
+}
+
+func (c ctx_file) Seek(offset int64, whence int) (int64, error) {
+   return c.f.Seek(offset, whence)
+}
+
+// src, stopping once ctx is done
+func with_context(ctx context.Context, src io.Reader) io.Reader {
+   if f, is_file := src.(*os.File); is_file {
+      return ctx_file{ctx_reader{ctx, f}, f}
+   }
+   return ctx_reader{ctx, src}
+}
@@ -0,0 +1,44 @@
+// Gotilities - cat
+// Author: prbrown
+//
+// --status-format=json, how each input went written to stderr at the end as
+// a JSON array, for scripts that would otherwise read the cat: lines.
+package main
+
+import "strconv"
+
+// input_status is how one input went: the bytes read of it, whether it was
+// written in full, and the last error given about it
+type input_status struct {
+   name string
+   bytes int64
+   ok bool
+   reason string
+}
+
+// appends the statuses as
+// [{"name":"a","bytes":N,"status":"ok"},{"name":"b","bytes":0,"status":"error","reason":"..."}]
+// and a newline
+func append_status_json(dst []byte, statuses []input_status) []byte {
+   dst = append(dst, '[')
+   for i, s := range statuses {
+      if i > 0 {
+         dst = append(dst, ',')
+      }
+      dst = append(dst, `{"name":`...)
+      dst = append_json_string(dst, []byte(s.name))
+      dst = append(dst, `,"bytes":`...)
+      dst = strconv.AppendInt(dst, s.bytes, 10)
+      if s.ok {
+         dst = append(dst, `,"status":"ok"`...)
+      } else {
+         dst = append(dst, `,"status":"error"`...)
+      }
+      if s.reason != "" {
+         dst = append(dst, `,"reason":`...)
+         dst = append_json_string(dst, []byte(s.reason))
+      }
+      dst = append(dst, '}')
+   }
+   return append(dst, ']', '\n')
+}
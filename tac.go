@@ -0,0 +1,79 @@
+// Gotilities - tac
+// Author: prbrown
+//
+// Concatenate and print lines in reverse, as GNU tac does.
+package main
+
+import "io"
+import "bufio"
+import "bytes"
+
+// Tac writes the lines of src to dst, last line first. A final line without
+// a newline is written as is and so runs into the line printed after it,
+// matching GNU tac.
+func Tac(dst io.Writer, src io.Reader) error {
+   return tac(dst, src, '\n', IO_BLK_SIZE_DEFAULT)
+}
+
+// tac reads seekable input backwards blk_size bytes at a time and holds
+// anything else in memory in full. Records end with sep.
+func tac(dst io.Writer, src io.Reader, sep byte, blk_size int64) error {
+   var tail []byte // input not yet written, ends with its last record
+   var pos, start int64
+
+   seeker, can_seek := src.(io.Seeker)
+   if can_seek {
+      var ok error
+      if start, ok = seeker.Seek(0, io.SeekCurrent); ok == nil {
+         pos, ok = seeker.Seek(0, io.SeekEnd)
+      }
+      can_seek = ok == nil
+   }
+   if !can_seek {
+      all, ok := io.ReadAll(src)
+      if ok != nil {
+         return ok
+      }
+      tail = all
+   }
+
+   out := bufio.NewWriterSize(dst, int(blk_size))
+
+   for ;; {
+      // write the last record of tail once the sep ending the one before it
+      // is in view
+      if len(tail) > 0 {
+         if i := bytes.LastIndexByte(tail[:len(tail)-1], sep); i >= 0 {
+            if _, ok := out.Write(tail[i+1:]); ok != nil {
+               return ok
+            }
+            tail = tail[:i+1]
+            continue
+         }
+      }
+
+      // (--start-offset) a start at or past the end leaves nothing to read
+      if pos <= start {
+         if _, ok := out.Write(tail); ok != nil {
+            return ok
+         }
+         return out.Flush()
+      }
+
+      // prepend the previous block
+      n := blk_size
+      if pos-start < n {
+         n = pos-start
+      }
+      pos -= n
+
+      block := make([]byte, n, n+int64(len(tail)))
+      if _, ok := seeker.Seek(pos, io.SeekStart); ok != nil {
+         return ok
+      }
+      if _, ok := io.ReadFull(src, block); ok != nil {
+         return ok
+      }
+      tail = append(block, tail...)
+   }
+}
@@ -0,0 +1,166 @@
+// Gotilities - tail
+// Author: prbrown
+//
+// Output the last part of the input.
+package main
+
+import "io"
+import "bytes"
+
+// Tail writes the last n lines of src to dst, or the last n bytes when
+// byteMode is set. Seekable input is searched from the end, so only the part
+// written is read; other input is read through, keeping the last n lines or
+// bytes.
+func Tail(dst io.Writer, src io.Reader, n int, byteMode bool) error {
+   return tail(dst, src, n, byteMode, '\n', IO_BLK_SIZE_DEFAULT)
+}
+
+// tail is Tail of the lines ending with sep
+func tail(dst io.Writer, src io.Reader, n int, byte_mode bool, sep byte, blk_size int64) error {
+   if n <= 0 {
+      return nil
+   }
+
+   if seeker, can_seek := src.(io.Seeker); can_seek {
+      start, ok := seeker.Seek(0, io.SeekCurrent)
+      var end int64
+      if ok == nil {
+         end, ok = seeker.Seek(0, io.SeekEnd)
+      }
+      if ok == nil {
+         return tail_seek(dst, src, seeker, start, end, n, byte_mode, sep, blk_size)
+      }
+   }
+
+   if byte_mode {
+      ring := &byte_ring{buf: make([]byte, n)}
+      if _, ok := io.CopyBuffer(ring, src, make([]byte, blk_size)); ok != nil {
+         return ok
+      }
+      older, newer := ring.parts()
+      if _, ok := dst.Write(older); ok != nil {
+         return ok
+      }
+      _, ok := dst.Write(newer)
+      return ok
+   }
+
+   // the last n lines, lines[next] being the oldest once there are n
+   var lines [][]byte
+   next := 0
+   ls := new_line_scanner(src, blk_size)
+   ls.sep = sep
+   for ;; {
+      line, ok := ls.next()
+      if ok == io.EOF {
+         break
+      } else if ok != nil {
+         return ok
+      }
+
+      if len(lines) < n {
+         lines = append(lines, append([]byte(nil), line...))
+      } else {
+         lines[next] = append(lines[next][:0], line...)
+         next = (next+1) % n
+      }
+   }
+
+   for i := range lines {
+      if _, ok := dst.Write(lines[(next+i) % len(lines)]); ok != nil {
+         return ok
+      }
+   }
+   return nil
+}
+
+// tail_seek writes the tail of the region [start, end) of a seekable input
+func tail_seek(dst io.Writer, src io.Reader, seeker io.Seeker, start int64, end int64, n int, byte_mode bool, sep byte, blk_size int64) error {
+   from := start
+   if byte_mode {
+      if end-start > int64(n) {
+         from = end-int64(n)
+      }
+   } else {
+      // scan back for the sep ending the line before the last n; the sep
+      // ending the input closes the last line and isn't counted
+      buf := make([]byte, blk_size)
+      pos := end
+      count := 0
+      for pos > start && from == start {
+         size := blk_size
+         if pos-start < size {
+            size = pos-start
+         }
+         pos -= size
+
+         if _, ok := seeker.Seek(pos, io.SeekStart); ok != nil {
+            return ok
+         }
+         if _, ok := io.ReadFull(src, buf[:size]); ok != nil {
+            return ok
+         }
+
+         block := buf[:size]
+         if pos+size == end {
+            block = block[:size-1]
+         }
+         for ;; {
+            i := bytes.LastIndexByte(block, sep)
+            if i < 0 {
+               break
+            }
+            count++
+            if count == n {
+               from = pos+int64(i)+1
+               break
+            }
+            block = block[:i]
+         }
+      }
+   }
+
+   if _, ok := seeker.Seek(from, io.SeekStart); ok != nil {
+      return ok
+   }
+   _, ok := io.CopyBuffer(dst, src, make([]byte, blk_size))
+   return ok
+}
+
+// byte_ring keeps the last len(buf) bytes written to it
+type byte_ring struct {
+   buf []byte
+   pos int   // where the next byte goes, the oldest byte once full
+   full bool
+}
+
+func (r *byte_ring) Write(p []byte) (int, error) {
+   n := len(p)
+   size := len(r.buf)
+   if size == 0 {
+      return n, nil
+   }
+
+   if n >= size {
+      copy(r.buf, p[n-size:])
+      r.pos = 0
+      r.full = true
+      return n, nil
+   }
+
+   c := copy(r.buf[r.pos:], p)
+   copy(r.buf, p[c:]) // wrap around
+   if r.pos+n >= size {
+      r.full = true
+   }
+   r.pos = (r.pos+n) % size
+   return n, nil
+}
+
+// parts returns the kept bytes, oldest first, split where the ring wraps
+func (r *byte_ring) parts() ([]byte, []byte) {
+   if !r.full {
+      return r.buf[:r.pos], nil
+   }
+   return r.buf[r.pos:], r.buf[:r.pos]
+}
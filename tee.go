@@ -0,0 +1,52 @@
+// Gotilities - tee
+// Author: prbrown
+//
+// Fan the output of Cat out to several writers, as tee does.
+package main
+
+import "io"
+import "errors"
+
+// CatTo applies opts to src once and writes the result to every writer in
+// dsts. By default the first failing writer stops the copy and its error is
+// returned. With opts.TeeContinue a failing writer is dropped and the copy
+// goes on to the rest; their errors are returned together at the end, or
+// as soon as no writer is left.
+func CatTo(dsts []io.Writer, src io.Reader, opts Options) error {
+   tee := &tee_writer{dsts: append([]io.Writer(nil), dsts...), keep_going: opts.TeeContinue}
+   _, ok := Cat(tee, src, opts)
+   if ok != nil {
+      return ok
+   }
+   return errors.Join(tee.errs...)
+}
+
+type tee_writer struct {
+   dsts []io.Writer // still writable
+   keep_going bool
+   errs []error     // from dropped writers
+}
+
+func (t *tee_writer) Write(p []byte) (int, error) {
+   live := t.dsts[:0]
+   for _, dst := range t.dsts {
+      n_written, ok := dst.Write(p)
+      if ok == nil && n_written != len(p) {
+         ok = io.ErrShortWrite
+      }
+      if ok != nil {
+         if !t.keep_going {
+            return 0, ok
+         }
+         t.errs = append(t.errs, ok)
+         continue
+      }
+      live = append(live, dst)
+   }
+   t.dsts = live
+
+   if len(t.dsts) == 0 && len(t.errs) > 0 {
+      return 0, errors.Join(t.errs...)
+   }
+   return len(p), nil
+}
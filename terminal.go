@@ -0,0 +1,18 @@
+// Gotilities - cat
+// Author: prbrown
+//
+// Telling a terminal from a file or pipe, for the options that default
+// differently on one.
+package main
+
+import "os"
+import "syscall"
+import "unsafe"
+
+// whether f is a terminal, the TCGETS ioctl succeeding only on one. A
+// variable so that tests can stand in for a terminal.
+var is_terminal = func(f *os.File) bool {
+   var termios syscall.Termios
+   _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), syscall.TCGETS, uintptr(unsafe.Pointer(&termios)))
+   return errno == 0
+}
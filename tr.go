@@ -0,0 +1,199 @@
+// Gotilities - tr
+// Author: prbrown
+//
+// --translate, --delete, --squeeze-repeats and --ascii-only, bytes mapped to
+// others, dropped or run together as they are read, ahead of everything else
+// cat does to them.
+package main
+
+import "io"
+import "fmt"
+import "errors"
+
+// tr_table is what --translate, --delete, --squeeze-repeats and --ascii-only
+// make of each byte. last is the byte it let through before, so a run is squeezed across
+// reads and files.
+type tr_table struct {
+   to [256]byte
+   del [256]bool
+   squeeze [256]bool
+   last int // -1 before the first byte
+}
+
+// the table of opts.Translate, Delete, SqueezeRepeats and AsciiOnly, nil when
+// none is set
+func new_tr_table(opts Options) (*tr_table, error) {
+   translate, del := opts.Translate, opts.Delete
+   if translate == "" && del == "" && opts.SqueezeRepeats == "" && opts.AsciiOnly == "" {
+      return nil, nil
+   }
+   t := &tr_table{last: -1}
+   for i := range t.to {
+      t.to[i] = byte(i)
+   }
+
+   if del != "" {
+      set, ok := parse_tr_set(del)
+      if ok != nil {
+         return nil, ok
+      }
+      for _, ch := range set {
+         t.del[ch] = true
+      }
+   }
+
+   if opts.SqueezeRepeats != "" {
+      set, ok := parse_tr_set(opts.SqueezeRepeats)
+      if ok != nil {
+         return nil, ok
+      }
+      for _, ch := range set {
+         t.squeeze[ch] = true
+      }
+   }
+
+   if translate != "" {
+      at := tr_split(translate)
+      if at < 0 {
+         return nil, errors.New("missing ':' between SET1 and SET2")
+      }
+      from, ok := parse_tr_set(translate[:at])
+      if ok != nil {
+         return nil, ok
+      }
+      to, ok := parse_tr_set(translate[at+1:])
+      if ok != nil {
+         return nil, ok
+      }
+      if len(to) == 0 {
+         return nil, errors.New("SET2 must not be empty")
+      }
+      // as tr, a short SET2 takes its last byte on to the end of SET1
+      for i, ch := range from {
+         t.to[ch] = to[min(i, len(to)-1)]
+      }
+   }
+
+   // (--ascii-only) of the bytes Translate leaves, those past ASCII
+   for i, ch := range t.to {
+      if ch < 0x80 {
+         continue
+      }
+      switch opts.AsciiOnly {
+      case "drop":
+         t.del[i] = true
+      case "flag":
+         t.to[i] = '?'
+      }
+   }
+   return t, nil
+}
+
+// the index of the ':' between SET1 and SET2, -1 when there is none
+func tr_split(s string) int {
+   for i := 0; i < len(s); i++ {
+      if s[i] == '\\' {
+         i++
+      } else if s[i] == ':' {
+         return i
+      }
+   }
+   return -1
+}
+
+// the bytes of a set, in order: each byte as itself, LO-HI for the bytes
+// from LO to HI, and \n, \t, \r, \0, \\, \: and \- for those bytes
+func parse_tr_set(s string) ([]byte, error) {
+   var set []byte
+   for len(s) > 0 {
+      lo, n, ok := tr_byte(s)
+      if ok != nil {
+         return nil, ok
+      }
+      s = s[n:]
+      if len(s) < 2 || s[0] != '-' {
+         set = append(set, lo)
+         continue
+      }
+
+      hi, n, ok := tr_byte(s[1:])
+      if ok != nil {
+         return nil, ok
+      }
+      s = s[1+n:]
+      if hi < lo {
+         return nil, fmt.Errorf("range '%c-%c' is in reverse order", lo, hi)
+      }
+      for ch := int(lo); ch <= int(hi); ch++ {
+         set = append(set, byte(ch))
+      }
+   }
+   return set, nil
+}
+
+// the byte s starts with, undoing an escape, and the length it takes up
+func tr_byte(s string) (byte, int, error) {
+   if s[0] != '\\' {
+      return s[0], 1, nil
+   }
+   if len(s) == 1 {
+      return 0, 0, errors.New("'\\' at the end of a set")
+   }
+   switch s[1] {
+   case 'n':
+      return '\n', 2, nil
+   case 't':
+      return '\t', 2, nil
+   case 'r':
+      return '\r', 2, nil
+   case '0':
+      return 0, 2, nil
+   case '\\', ':', '-':
+      return s[1], 2, nil
+   }
+   return 0, 0, fmt.Errorf("invalid escape '\\%c' in a set", s[1])
+}
+
+// apply maps, drops and squeezes the bytes of b in place, returning what is
+// left. A run is of the bytes made by Translate, as tr -s.
+func (t *tr_table) apply(b []byte) []byte {
+   n := 0
+   for _, ch := range b {
+      if t.del[ch] {
+         continue
+      }
+      ch = t.to[ch]
+      if t.squeeze[ch] && int(ch) == t.last {
+         continue
+      }
+      t.last = int(ch)
+      b[n] = ch
+      n++
+   }
+   return b[:n]
+}
+
+// tr_reader reads src through a tr_table
+type tr_reader struct {
+   src io.Reader
+   t *tr_table
+}
+
+// the input as st's tr_table has it, src itself when there is none
+func new_tr_reader(src io.Reader, st *cat_state) io.Reader {
+   if st.tr == nil {
+      return src
+   }
+   return &tr_reader{src: src, t: st.tr}
+}
+
+func (r *tr_reader) Read(p []byte) (int, error) {
+   for ;; {
+      n_read, ok := r.src.Read(p)
+      n := len(r.t.apply(p[:n_read]))
+      if n > 0 || ok != nil || n_read == 0 {
+         return n, ok
+      }
+      // all of it deleted, read on
+   }
+}
@@ -0,0 +1,63 @@
+// Gotilities - cat
+// Author: prbrown
+//
+// --trim-head-bytes and --trim-tail-bytes, so many bytes left off the start
+// and the end of the whole output.
+package main
+
+import "io"
+
+// trim_writer passes on what is written to it less its first head bytes and,
+// held back in a byte_ring until more is written, its last tail bytes; those
+// still held at the end are never written
+type trim_writer struct {
+   dst io.Writer
+   head int64 // still to be dropped
+   tail *byte_ring
+}
+
+func new_trim_writer(dst io.Writer, head int64, tail int64) *trim_writer {
+   return &trim_writer{dst: dst, head: head, tail: &byte_ring{buf: make([]byte, tail)}}
+}
+
+func (w *trim_writer) Write(p []byte) (int, error) {
+   n_given := len(p)
+   n := int(min(w.head, int64(len(p))))
+   p = p[n:]
+   w.head -= int64(n)
+
+   if ok := w.tail.shift(p, w.dst); ok != nil {
+      return 0, ok
+   }
+   return n_given, nil
+}
+
+// shift writes p into the ring, first writing to dst, oldest first, the bytes
+// that then no longer fit
+func (r *byte_ring) shift(p []byte, dst io.Writer) error {
+   size := len(r.buf)
+   held := r.pos
+   if r.full {
+      held = size
+   }
+
+   if over := held + len(p) - size; over > 0 {
+      older, newer := r.parts()
+      out := min(over, held)
+      n := min(out, len(older))
+      if _, ok := dst.Write(older[:n]); ok != nil {
+         return ok
+      }
+      if _, ok := dst.Write(newer[:out-n]); ok != nil {
+         return ok
+      }
+      if over > held {
+         if _, ok := dst.Write(p[:over-held]); ok != nil {
+            return ok
+         }
+         p = p[over-held:]
+      }
+   }
+   r.Write(p)
+   return nil
+}
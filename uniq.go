@@ -0,0 +1,82 @@
+// Gotilities - uniq
+// Author: prbrown
+//
+// --uniq and --uniq-count, runs of the same output line written once.
+package main
+
+import "io"
+import "fmt"
+import "bytes"
+
+// uniq_writer passes on the lines written to it with each run of equal lines
+// as one, after a count of the run, as uniq -c, when counts is set. A line is
+// held in full until the next shows whether it repeats, so the memory used
+// grows with the longest line.
+type uniq_writer struct {
+   dst io.Writer
+   counts bool
+   sep byte     // ends each line
+   prev []byte  // the line of the run, with its sep if it has one
+   n_prev int   // the length of the run, 0 before the first line
+   cur []byte   // the line being written
+}
+
+func new_uniq_writer(dst io.Writer, counts bool, sep byte) *uniq_writer {
+   return &uniq_writer{dst: dst, counts: counts, sep: sep}
+}
+
+func (u *uniq_writer) Write(p []byte) (int, error) {
+   n_given := len(p)
+   for len(p) > 0 {
+      i := bytes.IndexByte(p, u.sep)
+      if i < 0 {
+         u.cur = append(u.cur, p...)
+         break
+      }
+      u.cur = append(u.cur, p[:i+1]...)
+      p = p[i+1:]
+      if ok := u.end_line(); ok != nil {
+         return n_given - len(p), ok
+      }
+   }
+   return n_given, nil
+}
+
+// the line in cur is complete, or ends the output
+func (u *uniq_writer) end_line() error {
+   if u.n_prev > 0 && bytes.Equal(bytes.TrimSuffix(u.cur, []byte{u.sep}), bytes.TrimSuffix(u.prev, []byte{u.sep})) {
+      u.n_prev++
+      u.cur = u.cur[:0]
+      return nil
+   }
+   ok := u.write_run()
+   u.prev, u.cur = u.cur, u.prev[:0]
+   u.n_prev = 1
+   return ok
+}
+
+// writes out the run of prev
+func (u *uniq_writer) write_run() error {
+   if u.n_prev == 0 {
+      return nil
+   }
+   if u.counts {
+      if _, ok := fmt.Fprintf(u.dst, "%7d ", u.n_prev); ok != nil {
+         return ok
+      }
+   }
+   _, ok := u.dst.Write(u.prev)
+   return ok
+}
+
+// Close writes the last run
+func (u *uniq_writer) Close() error {
+   if len(u.cur) > 0 {
+      if ok := u.end_line(); ok != nil {
+         return ok
+      }
+   }
+   ok := u.write_run()
+   u.n_prev = 0
+   return ok
+}
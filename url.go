@@ -0,0 +1,44 @@
+// Gotilities - cat
+// Author: prbrown
+//
+// http:// and https:// arguments, fetched and written like files.
+package main
+
+import "math"
+import "strings"
+import "net/http"
+
+// whether a FILE argument names a URL rather than a path
+func is_url(fName string) bool {
+   return strings.HasPrefix(fName, "http://") || strings.HasPrefix(fName, "https://")
+}
+
+// handle_url is handle_file for a URL, the response body being the input
+func handle_url(cfg *Config, st *cat_state, url string, out_bSize int64) bool {
+   if cfg.repeat > 1 {
+      print_file_error(cfg, url, "cannot repeat an input that cannot seek")
+      return false
+   }
+
+   resp, ok := http.Get(url)
+   if ok != nil {
+      print_error(cfg, ok)
+      return false
+   }
+   defer resp.Body.Close()
+
+   if resp.StatusCode < 200 || resp.StatusCode > 299 {
+      print_file_error(cfg, url, resp.Status)
+      return false
+   }
+
+   var size int64
+   if resp.ContentLength > 0 {
+      size = resp.ContentLength
+   }
+   in_size := int64(math.Max(float64(IO_BLK_SIZE_DEFAULT), float64(out_bSize)))
+   if cfg.block_size > 0 {
+      in_size = cfg.block_size
+   }
+   return handle_input(cfg, st, resp.Body, url, size, in_size, out_bSize)
+}
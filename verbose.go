@@ -0,0 +1,81 @@
+// Gotilities - cat
+// Author: prbrown
+//
+// Error messages, and the fuller form of -V with the failing call and errno.
+package main
+
+import "os"
+import "fmt"
+import "errors"
+import "syscall"
+
+// names of the errno values cat is likely to meet
+var errno_names = map[syscall.Errno]string{
+   syscall.EPERM: "EPERM",
+   syscall.ENOENT: "ENOENT",
+   syscall.EINTR: "EINTR",
+   syscall.EIO: "EIO",
+   syscall.ENXIO: "ENXIO",
+   syscall.EBADF: "EBADF",
+   syscall.EAGAIN: "EAGAIN",
+   syscall.ENOMEM: "ENOMEM",
+   syscall.EACCES: "EACCES",
+   syscall.EFAULT: "EFAULT",
+   syscall.EBUSY: "EBUSY",
+   syscall.EEXIST: "EEXIST",
+   syscall.ENODEV: "ENODEV",
+   syscall.ENOTDIR: "ENOTDIR",
+   syscall.EISDIR: "EISDIR",
+   syscall.EINVAL: "EINVAL",
+   syscall.ENFILE: "ENFILE",
+   syscall.EMFILE: "EMFILE",
+   syscall.ENOTTY: "ENOTTY",
+   syscall.ETXTBSY: "ETXTBSY",
+   syscall.EFBIG: "EFBIG",
+   syscall.ENOSPC: "ENOSPC",
+   syscall.ESPIPE: "ESPIPE",
+   syscall.EROFS: "EROFS",
+   syscall.EPIPE: "EPIPE",
+   syscall.ENAMETOOLONG: "ENAMETOOLONG",
+   syscall.ELOOP: "ELOOP",
+   syscall.EOVERFLOW: "EOVERFLOW",
+   syscall.EDQUOT: "EDQUOT",
+}
+
+// print_error writes an error to stderr. With -V a failed call on a path
+// reads as "cat: open 'foo': permission denied (EACCES)".
+func print_error(cfg *Config, ok error) {
+   if !cfg.verbose {
+      cfg.last_error = ok.Error()
+      fmt.Fprintln(os.Stderr, "cat: ", ok)
+      return
+   }
+   cfg.last_error = verbose_message(ok)
+   fmt.Fprintln(os.Stderr, "cat: " + cfg.last_error)
+}
+
+// print_file_error writes msg about the file fName to stderr, as
+// "cat: fName: msg"
+func print_file_error(cfg *Config, fName string, msg string) {
+   cfg.last_error = msg
+   fmt.Fprintf(os.Stderr, "cat: %s: %s\n", fName, msg)
+}
+
+func verbose_message(ok error) string {
+   msg := ok.Error()
+
+   var path_ok *os.PathError
+   if errors.As(ok, &path_ok) {
+      msg = fmt.Sprintf("%s '%s': %v", path_ok.Op, path_ok.Path, path_ok.Err)
+   }
+
+   var errno syscall.Errno
+   if errors.As(ok, &errno) {
+      name, found := errno_names[errno]
+      if !found {
+         name = fmt.Sprintf("errno %d", int(errno))
+      }
+      msg += " (" + name + ")"
+   }
+   return msg
+}
@@ -0,0 +1,122 @@
+// Gotilities - cat
+// Author: prbrown
+//
+// -R, --recursive, the regular files under each directory FILE read in
+// place of the directory.
+package main
+
+import "os"
+import "strings"
+import "syscall"
+import "io/fs"
+import "path/filepath"
+
+// walk_files puts in place of each directory of cfg.Files the regular files
+// under it, in sorted order, and says whether every directory could be read.
+// Symbolic links met along the way are passed over, unless follow (-L), as
+// are files and directories matching an --exclude pattern.
+func walk_files(cfg *Config, follow bool) bool {
+   ret := true
+   var files []string
+   fds := make(map[int]int, len(cfg.fds))
+
+   for i, name := range cfg.Files {
+      if fd, is_fd := cfg.fds[i]; is_fd {
+         fds[len(files)] = fd
+         files = append(files, name)
+         continue
+      }
+      if info, ok := os.Stat(name); name == "-" || is_url(name) || ok != nil || !info.IsDir() {
+         files = append(files, name) // read, or failed, as any other FILE
+         continue
+      }
+
+      w := walker{cfg: cfg, follow: follow, seen: make(map[[2]uint64]bool)}
+      ret = w.walk(name) && ret
+      files = append(files, w.files...)
+   }
+
+   cfg.Files, cfg.fds = files, fds
+   return ret
+}
+
+type walker struct {
+   cfg *Config
+   follow bool
+   seen map[[2]uint64]bool // directories walked, by device and inode, so links cannot loop
+   files []string
+}
+
+func (w *walker) walk(root string) bool {
+   ret := true
+   if !w.first_visit(root) {
+      return true
+   }
+
+   // a trailing slash to go into root even when it is a link
+   if !strings.HasSuffix(root, "/") {
+      root += "/"
+   }
+   filepath.WalkDir(root, func(path string, d fs.DirEntry, ok error) error {
+      if ok != nil {
+         print_error(w.cfg, ok)
+         ret = false
+         return nil // on with the rest
+      }
+
+      if path != root && excluded(w.cfg.exclude, path) {
+         if d.IsDir() {
+            return fs.SkipDir
+         }
+         return nil
+      }
+
+      switch {
+      case d.Type().IsRegular():
+         w.files = append(w.files, path)
+      case d.IsDir() && path != root && !w.first_visit(path):
+         return fs.SkipDir
+      case d.Type() & fs.ModeSymlink != 0 && w.follow:
+         info, ok := os.Stat(path)
+         if ok != nil {
+            print_error(w.cfg, ok)
+            ret = false
+         } else if info.IsDir() {
+            ret = w.walk(path) && ret
+         } else if info.Mode().IsRegular() {
+            w.files = append(w.files, path)
+         }
+      }
+      return nil
+   })
+   return ret
+}
+
+// whether the directory at path is new to the walk
+func (w *walker) first_visit(path string) bool {
+   var dir_stat syscall.Stat_t
+   if syscall.Stat(path, &dir_stat) != nil {
+      return true // failing later, where it is reported
+   }
+   id := [2]uint64{uint64(dir_stat.Dev), dir_stat.Ino}
+   if w.seen[id] {
+      return false
+   }
+   w.seen[id] = true
+   return true
+}
+
+// whether path matches one of the --exclude patterns, by its base name or,
+// for a pattern with a slash, in full
+func excluded(patterns []string, path string) bool {
+   for _, pattern := range patterns {
+      name := filepath.Base(path)
+      if strings.Contains(pattern, "/") {
+         name = path
+      }
+      if matched, _ := filepath.Match(pattern, name); matched {
+         return true
+      }
+   }
+   return false
+}
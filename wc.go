@@ -0,0 +1,72 @@
+// Gotilities - wc
+// Author: prbrown
+//
+// Count the lines, words and bytes of the input.
+package main
+
+import "io"
+import "fmt"
+
+// Count tallies the lines, words and bytes of src in one pass. As with GNU
+// wc in the C locale, lines are newline characters and a word is a run of
+// printable non-space bytes; other non-printing bytes neither start nor end
+// a word.
+func Count(src io.Reader) (lines, words, bytes int64, err error) {
+   return count(src, IO_BLK_SIZE_DEFAULT)
+}
+
+func count(src io.Reader, blk_size int64) (lines, words, bytes int64, err error) {
+   buf := make([]byte, blk_size)
+   in_word := false
+
+   for ;; {
+      n_read, ok := src.Read(buf)
+      bytes += int64(n_read)
+
+      for _, ch := range buf[:n_read] {
+         switch ch {
+         case '\n':
+            lines++
+            fallthrough
+         case ' ', '\t', '\v', '\f', '\r':
+            if in_word {
+               words++
+               in_word = false
+            }
+         default:
+            if ch > ' ' && ch < 0x7F {
+               in_word = true
+            }
+         }
+      }
+
+      if ok != nil {
+         if in_word {
+            words++
+         }
+         if ok == io.EOF {
+            ok = nil
+         }
+         return lines, words, bytes, ok
+      }
+   }
+}
+
+// count_tally is --count, one wc style line per file, the counts summed
+// for a total line after them
+type count_tally struct {
+   lines, words, bytes int64
+}
+
+func (c *count_tally) report(src io.Reader, name string, blk_size int64) (string, error) {
+   lines, words, bytes, ok := count(src, blk_size)
+   c.lines += lines
+   c.words += words
+   c.bytes += bytes
+   return fmt.Sprintf("%7d %7d %7d %s\n", lines, words, bytes, name), ok
+}
+
+// the sums of every file counted, as wc does for more than one
+func (c *count_tally) total() string {
+   return fmt.Sprintf("%7d %7d %7d total\n", c.lines, c.words, c.bytes)
+}
@@ -0,0 +1,69 @@
+// Gotilities - fmt
+// Author: prbrown
+//
+// --wrap, paragraphs refilled with as many words to a line as fit, as fmt
+// does, where fold breaks each long line as it stands.
+package main
+
+import "io"
+import "bufio"
+import "bytes"
+import "unicode/utf8"
+
+// Wrap writes the words of src to dst a paragraph at a time, joined by
+// single spaces into lines of at most width columns; a word wider than that
+// is a line to itself. Blank lines end paragraphs, and are written as they
+// are.
+func Wrap(dst io.Writer, src io.Reader, width int) error {
+   return wrap(dst, src, width, IO_BLK_SIZE_DEFAULT)
+}
+
+func wrap(dst io.Writer, src io.Reader, width int, blk_size int64) error {
+   ls := new_line_scanner(src, blk_size)
+   out := bufio.NewWriterSize(dst, int(blk_size))
+   var line_out []byte // the words of the output line so far, short of its newline
+   column := 0
+
+   end_line := func() {
+      if len(line_out) > 0 {
+         out.Write(line_out)
+         out.WriteByte('\n')
+         line_out = line_out[:0]
+         column = 0
+      }
+   }
+
+   for ;; {
+      line, ok := ls.next()
+      if ok == io.EOF {
+         end_line()
+         return out.Flush()
+      } else if ok != nil {
+         out.Flush()
+         return ok
+      }
+
+      words := bytes.Fields(line)
+      if len(words) == 0 {
+         // a blank line, the paragraph is done
+         end_line()
+         if ok := out.WriteByte('\n'); ok != nil {
+            return ok
+         }
+         continue
+      }
+
+      for _, word := range words {
+         word_width := utf8.RuneCount(word)
+         if column > 0 && column + 1 + word_width > width {
+            end_line()
+         }
+         if column > 0 {
+            line_out = append(line_out, ' ')
+            column++
+         }
+         line_out = append(line_out, word...)
+         column += word_width
+      }
+   }
+}
@@ -0,0 +1,113 @@
+// Gotilities - cat
+// Author: prbrown
+//
+// Writer, the push form of Cat.
+package main
+
+import "io"
+import "bytes"
+import "errors"
+
+var err_writer_closed = errors.New("write to closed Writer")
+
+// Writer applies the cat transformation to the bytes written to it, for
+// data that does not come from an io.Reader. Input is held back to the end
+// of its last complete line, or a block of it for a long line, until more is
+// written or Flush is called. Close flushes; dst is left open.
+type Writer struct {
+   st *cat_state
+   skip int64     // Options.StartOffset, still to be skipped
+   partial []byte // written after the last newline
+   in_buf []byte  // the bytes transformed, with the sentinel
+   tr_buf []byte  // a Write after Options.Translate and Delete
+   out_buf []byte
+   closed bool
+}
+
+// NewWriter returns a Writer applying opts on the way to dst.
+func NewWriter(dst io.Writer, opts Options) *Writer {
+   return &Writer{st: new_cat_state(dst, opts), skip: opts.StartOffset}
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+   if w.closed {
+      return 0, err_writer_closed
+   }
+   n_given := len(p)
+   if w.skip > 0 {
+      n := min(w.skip, int64(len(p)))
+      p = p[n:]
+      w.skip -= n
+   }
+   if w.st.tr != nil { // --translate and --delete, ahead of the rest
+      w.tr_buf = w.st.tr.apply(append(w.tr_buf[:0], p...))
+      p = w.tr_buf
+   }
+
+   if !w.st.transforms() {
+      w.st.stats.BytesRead += int64(len(p))
+      if _, ok := w.st.write(p); ok != nil && ok != err_max_bytes {
+         return 0, ok
+      }
+      return n_given, nil
+   }
+
+   w.partial = append(w.partial, p...)
+   end := bytes.LastIndexByte(w.partial, w.st.sep) + 1
+   if end == 0 && len(w.partial) >= int(IO_BLK_SIZE_DEFAULT) {
+      end = len(w.partial)
+      if w.partial[end-1] == '\r' {
+         end-- // a LF may follow in the next write
+      }
+   }
+   if end == 0 {
+      return n_given, nil
+   }
+
+   ok := w.transform(w.partial[:end])
+   w.partial = w.partial[:copy(w.partial, w.partial[end:])]
+   if ok != nil {
+      return 0, ok
+   }
+   return n_given, nil
+}
+
+// Flush transforms and writes all the input held back, a CR ending it taken
+// as a lone CR.
+func (w *Writer) Flush() error {
+   if len(w.partial) == 0 {
+      return nil
+   }
+   ok := w.transform(w.partial)
+   w.partial = w.partial[:0]
+   return ok
+}
+
+// Close flushes the Writer, after which Write fails.
+func (w *Writer) Close() error {
+   if w.closed {
+      return nil
+   }
+   w.closed = true
+   return w.Flush()
+}
+
+// the cat transformation of data, written to dst
+func (w *Writer) transform(data []byte) error {
+   w.st.stats.BytesRead += int64(len(data))
+   if w.st.opts.LineEnding != "" {
+      w.in_buf = from_cr(w.in_buf[:0], data, '\n')
+   } else if w.st.opts.StripCR {
+      w.in_buf = from_cr(w.in_buf[:0], data, '\r')
+   } else {
+      w.in_buf = append(w.in_buf[:0], data...)
+   }
+   w.in_buf = append(w.in_buf, w.st.sep) // sentinel
+
+   w.out_buf = w.st.transform(w.in_buf, w.out_buf[:0])
+   var ok error
+   if w.out_buf, ok = w.st.write_pending(w.out_buf); ok != nil && ok != err_max_bytes {
+      return ok
+   }
+   return nil
+}
@@ -0,0 +1,27 @@
+// Gotilities - yes
+// Author: prbrown
+//
+// --yes, a line written over and over in place of any input, as yes.
+package main
+
+import "io"
+import "context"
+
+// write_yes writes s and sep to dst until a write fails or ctx is done,
+// a block of them at a time
+func write_yes(ctx context.Context, dst io.Writer, s string, sep byte, blk_size int64) error {
+   line := append([]byte(s), sep)
+   buf := make([]byte, 0, blk_size)
+   for len(buf) == 0 || len(buf) + len(line) <= cap(buf) {
+      buf = append(buf, line...)
+   }
+
+   for ;; {
+      if ok := ctx.Err(); ok != nil {
+         return ok
+      }
+      if _, ok := dst.Write(buf); ok != nil {
+         return ok
+      }
+   }
+}